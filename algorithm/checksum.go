@@ -0,0 +1,218 @@
+package algorithm
+
+import (
+	"encoding/binary"
+)
+
+// Checksums which are not CRC, each of them has the same API as CRC:
+// X(data), XContinue(data, prev), AppendX(data) and VerifyX(data).
+// Multi-byte checksums are appended in little-endian as AppendCrc16 does,
+// except Adler-32 which is big-endian as in RFC 1950.
+// Reference:
+// https://en.wikipedia.org/wiki/Adler-32
+// https://en.wikipedia.org/wiki/Fletcher%27s_checksum
+// https://en.wikipedia.org/wiki/Longitudinal_redundancy_check
+
+const (
+	adler32Mod = 65521
+	// adler32Block is the largest n such that 255n(n+1)/2 + (n+1)(mod-1)
+	// fits in uint32, the modulo is taken once per block
+	adler32Block = 5552
+	// the same limits of Fletcher-16 in bytes and Fletcher-32 in words
+	fletcher16Block = 5802
+	fletcher32Block = 359
+)
+
+func Adler32(data []byte) uint32 {
+	return Adler32Continue(data, 1)
+}
+
+// Adler32Continue continues the calculation of Adler-32, prev is the checksum
+// of the data before
+func Adler32Continue(data []byte, prev uint32) uint32 {
+	s1, s2 := prev&0xFFFF, prev>>16
+	for len(data) > 0 {
+		n := len(data)
+		if n > adler32Block {
+			n = adler32Block
+		}
+		for _, d := range data[:n] {
+			s1 += uint32(d)
+			s2 += s1
+		}
+		s1 %= adler32Mod
+		s2 %= adler32Mod
+		data = data[n:]
+	}
+	return s2<<16 | s1
+}
+
+func AppendAdler32(data []byte) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], Adler32(data))
+	return append(data, buf[:]...)
+}
+
+func VerifyAdler32(data []byte) bool {
+	n := len(data) - 4
+	return n >= 0 && Adler32(data[:n]) == binary.BigEndian.Uint32(data[n:])
+}
+
+// Fletcher16 returns sum2<<8 | sum1, the sums are of bytes modulo 255
+func Fletcher16(data []byte) uint16 {
+	return Fletcher16Continue(data, 0)
+}
+
+func Fletcher16Continue(data []byte, prev uint16) uint16 {
+	s1, s2 := uint32(prev&0xFF), uint32(prev>>8)
+	for len(data) > 0 {
+		n := len(data)
+		if n > fletcher16Block {
+			n = fletcher16Block
+		}
+		for _, d := range data[:n] {
+			s1 += uint32(d)
+			s2 += s1
+		}
+		s1 %= 255
+		s2 %= 255
+		data = data[n:]
+	}
+	return uint16(s2<<8 | s1)
+}
+
+func AppendFletcher16(data []byte) []byte {
+	sum := Fletcher16(data)
+	return append(data, byte(sum), byte(sum>>8))
+}
+
+func VerifyFletcher16(data []byte) bool {
+	n := len(data) - 2
+	return n >= 0 &&
+		Fletcher16(data[:n]) == binary.LittleEndian.Uint16(data[n:])
+}
+
+// Fletcher32 returns sum2<<16 | sum1, the sums are of 16-bit little-endian
+// words modulo 65535, an odd length is padded with a zero byte
+func Fletcher32(data []byte) uint32 {
+	return Fletcher32Continue(data, 0)
+}
+
+// Fletcher32Continue continues the calculation of Fletcher-32, the data
+// before must have an even length
+func Fletcher32Continue(data []byte, prev uint32) uint32 {
+	s1, s2 := prev&0xFFFF, prev>>16
+	for len(data) > 0 {
+		n := len(data)
+		if n > fletcher32Block*2 {
+			n = fletcher32Block * 2
+		}
+		block := data[:n]
+		for len(block) >= 2 {
+			s1 += uint32(binary.LittleEndian.Uint16(block))
+			s2 += s1
+			block = block[2:]
+		}
+		if len(block) > 0 {
+			s1 += uint32(block[0])
+			s2 += s1
+		}
+		s1 %= 65535
+		s2 %= 65535
+		data = data[n:]
+	}
+	return s2<<16 | s1
+}
+
+func AppendFletcher32(data []byte) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], Fletcher32(data))
+	return append(data, buf[:]...)
+}
+
+func VerifyFletcher32(data []byte) bool {
+	n := len(data) - 4
+	return n >= 0 &&
+		Fletcher32(data[:n]) == binary.LittleEndian.Uint32(data[n:])
+}
+
+// Lrc returns the two's complement of the sum of data, as Modbus ASCII. The
+// sum of a message and its LRC is 0.
+func Lrc(data []byte) byte {
+	return LrcContinue(data, 0)
+}
+
+func LrcContinue(data []byte, prev byte) byte {
+	return -Sum8Continue(data, -prev)
+}
+
+func AppendLrc(data []byte) []byte {
+	return append(data, Lrc(data))
+}
+
+func VerifyLrc(data []byte) bool {
+	return len(data) > 0 && Sum8(data) == 0
+}
+
+// Xor8 returns the XOR of all bytes, as the checksum of NMEA 0183
+func Xor8(data []byte) byte {
+	return Xor8Continue(data, 0)
+}
+
+func Xor8Continue(data []byte, prev byte) byte {
+	for _, d := range data {
+		prev ^= d
+	}
+	return prev
+}
+
+func AppendXor8(data []byte) []byte {
+	return append(data, Xor8(data))
+}
+
+func VerifyXor8(data []byte) bool {
+	return len(data) > 0 && Xor8(data) == 0
+}
+
+// Sum8 returns the sum of all bytes modulo 256
+func Sum8(data []byte) byte {
+	return Sum8Continue(data, 0)
+}
+
+func Sum8Continue(data []byte, prev byte) byte {
+	for _, d := range data {
+		prev += d
+	}
+	return prev
+}
+
+func AppendSum8(data []byte) []byte {
+	return append(data, Sum8(data))
+}
+
+func VerifySum8(data []byte) bool {
+	n := len(data) - 1
+	return n >= 0 && Sum8(data[:n]) == data[n]
+}
+
+// Sum16 returns the sum of all bytes modulo 65536
+func Sum16(data []byte) uint16 {
+	return Sum16Continue(data, 0)
+}
+
+func Sum16Continue(data []byte, prev uint16) uint16 {
+	for _, d := range data {
+		prev += uint16(d)
+	}
+	return prev
+}
+
+func AppendSum16(data []byte) []byte {
+	sum := Sum16(data)
+	return append(data, byte(sum), byte(sum>>8))
+}
+
+func VerifySum16(data []byte) bool {
+	n := len(data) - 2
+	return n >= 0 && Sum16(data[:n]) == binary.LittleEndian.Uint16(data[n:])
+}
@@ -0,0 +1,115 @@
+package algorithm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hash/adler32"
+	"testing"
+)
+
+func TestAdler32(t *testing.T) {
+	assert := assert.New(t)
+	assert.EqualValues(Adler32(nil), 1)
+	assert.EqualValues(Adler32([]byte("Wikipedia")), 0x11E60398)
+	data := makeRandomData(100000)
+	assert.Equal(Adler32(data), adler32.Checksum(data))
+	assert.Equal(Adler32Continue(data[30000:], Adler32(data[:30000])),
+		Adler32(data))
+	// worst case of the deferred modulo
+	ff := make([]byte, 20000)
+	for i := range ff {
+		ff[i] = 0xFF
+	}
+	assert.Equal(Adler32(ff), adler32.Checksum(ff))
+	data = AppendAdler32([]byte("Wikipedia"))
+	assert.Equal(data[9:], []byte{0x11, 0xE6, 0x03, 0x98})
+	assert.True(VerifyAdler32(data))
+	data[0]++
+	assert.False(VerifyAdler32(data))
+	assert.False(VerifyAdler32([]byte{1, 2, 3}))
+}
+
+func TestFletcher16(t *testing.T) {
+	assert := assert.New(t)
+	assert.EqualValues(Fletcher16([]byte("abcde")), 0xC8F0)
+	assert.EqualValues(Fletcher16([]byte("abcdef")), 0x2057)
+	assert.EqualValues(Fletcher16([]byte("abcdefgh")), 0x0627)
+	assert.Equal(Fletcher16Continue([]byte("def"), Fletcher16([]byte("abc"))),
+		Fletcher16([]byte("abcdef")))
+	ff := make([]byte, 20000)
+	for i := range ff {
+		ff[i] = 0xFF
+	}
+	assert.EqualValues(Fletcher16(ff), 0)
+	data := AppendFletcher16([]byte("abcde"))
+	assert.Equal(data[5:], []byte{0xF0, 0xC8})
+	assert.True(VerifyFletcher16(data))
+	data[0]++
+	assert.False(VerifyFletcher16(data))
+	assert.False(VerifyFletcher16([]byte{1}))
+}
+
+func TestFletcher32(t *testing.T) {
+	assert := assert.New(t)
+	assert.EqualValues(Fletcher32([]byte("abcde")), 0xF04FC729)
+	assert.EqualValues(Fletcher32([]byte("abcdef")), 0x56502D2A)
+	assert.EqualValues(Fletcher32([]byte("abcdefgh")), 0xEBE19591)
+	assert.Equal(Fletcher32Continue([]byte("cdefgh"), Fletcher32([]byte("ab"))),
+		Fletcher32([]byte("abcdefgh")))
+	ff := make([]byte, 20000)
+	for i := range ff {
+		ff[i] = 0xFF
+	}
+	assert.EqualValues(Fletcher32(ff), 0)
+	data := AppendFletcher32([]byte("abcde"))
+	assert.Equal(data[5:], []byte{0x29, 0xC7, 0x4F, 0xF0})
+	assert.True(VerifyFletcher32(data))
+	data[0]++
+	assert.False(VerifyFletcher32(data))
+	assert.False(VerifyFletcher32([]byte{1, 2, 3}))
+}
+
+func TestLrc(t *testing.T) {
+	assert := assert.New(t)
+	data := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0A}
+	assert.EqualValues(Lrc(data), 0xF2)
+	assert.EqualValues(LrcContinue(data[3:], Lrc(data[:3])), 0xF2)
+	data = AppendLrc(data)
+	assert.EqualValues(data[6], 0xF2)
+	assert.True(VerifyLrc(data))
+	data[0]++
+	assert.False(VerifyLrc(data))
+	assert.False(VerifyLrc(nil))
+}
+
+func TestXor8(t *testing.T) {
+	assert := assert.New(t)
+	nmea := []byte("GPGLL,5300.97914,N,00259.98174,E,125926,A")
+	assert.EqualValues(Xor8(nmea), 0x28)
+	assert.EqualValues(Xor8Continue(nmea[10:], Xor8(nmea[:10])), 0x28)
+	data := AppendXor8(nmea)
+	assert.True(VerifyXor8(data))
+	data[0]++
+	assert.False(VerifyXor8(data))
+	assert.False(VerifyXor8(nil))
+}
+
+func TestSum(t *testing.T) {
+	assert := assert.New(t)
+	assert.EqualValues(Sum8(checkData), 0xDD)
+	assert.EqualValues(Sum8Continue(checkData[4:], Sum8(checkData[:4])), 0xDD)
+	assert.EqualValues(Sum16(checkData), 0x01DD)
+	assert.EqualValues(
+		Sum16Continue(checkData[4:], Sum16(checkData[:4])), 0x01DD)
+	data := AppendSum8(append([]byte(nil), checkData...))
+	assert.EqualValues(data[9], 0xDD)
+	assert.True(VerifySum8(data))
+	data[0]++
+	assert.False(VerifySum8(data))
+	assert.False(VerifySum8(nil))
+	data = AppendSum16(append([]byte(nil), checkData...))
+	assert.Equal(data[9:], []byte{0xDD, 0x01})
+	assert.True(VerifySum16(data))
+	data[0]++
+	assert.False(VerifySum16(data))
+	assert.False(VerifySum16([]byte{1}))
+}
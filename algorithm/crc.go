@@ -1,81 +1,164 @@
 package algorithm
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math/bits"
+	"sync"
 )
 
+// Config describes a CRC algorithm with the parameters of the Rocksoft model.
+// Name is the canonical name used by the catalogue, see CrcByName.
+// Width is the number of bits of the CRC (1-64) and Polynomial omits the
+// leading x^Width term. Check is the CRC of the ASCII string "123456789",
+// Residue is the register value after processing a message followed by its
+// correct CRC, before XorResult is applied. Configs outside the catalogue
+// must be written with keyed fields, a zero Width in the Crc*Configs lists
+// means the width of the list.
 type Config struct {
-	Polynomial uint32
-	InitValue  uint32
-	XorResult  uint32
+	Polynomial uint64
+	InitValue  uint64
+	XorResult  uint64
 	ReflectIn  bool
 	ReflectOut bool
+	Name       string
+	Width      uint
+	Check      uint64
+	Residue    uint64
 }
 
 // Reference:
 // http://crccalc.com/
 // http://www.sunshine2k.de/coding/javascript/crc/crc_js.html
 // http://www.sunshine2k.de/articles/coding/crc/understanding_crc.html
+// http://reveng.sourceforge.net/crc-catalogue/all.htm
 var (
+	Crc5Configs = []Config{
+		{0x09, 0x09, 0x00, false, false, "CRC-5/EPC-C1G2", 5, 0x00, 0x00},
+		{0x15, 0x00, 0x00, true, true, "CRC-5/G-704", 5, 0x07, 0x00},
+		{0x05, 0x1F, 0x1F, true, true, "CRC-5/USB", 5, 0x19, 0x06},
+	}
+	Crc7Configs = []Config{
+		{0x09, 0x00, 0x00, false, false, "CRC-7/MMC", 7, 0x75, 0x00},
+		{0x4F, 0x7F, 0x00, true, true, "CRC-7/ROHC", 7, 0x53, 0x00},
+		{0x45, 0x00, 0x00, false, false, "CRC-7/UMTS", 7, 0x61, 0x00},
+	}
 	Crc8Configs = []Config{
-		{0x07, 0x00, 0x00, false, false}, // CRC-8
-		{0x9B, 0xFF, 0x00, false, false}, // CRC-8/CDMA2000
-		{0x39, 0x00, 0x00, true, true},   // CRC-8/DARC
-		{0xD5, 0x00, 0x00, false, false}, // CRC-8/DVB-S2
-		{0x1D, 0xFF, 0x00, true, true},   // CRC-8/EBU
-		{0x1D, 0xFD, 0x00, false, false}, // CRC-8/I-CODE
-		{0x07, 0x00, 0x55, false, false}, // CRC-8/ITU
-		{0x31, 0x00, 0x00, true, true},   // CRC-8/MAXIM
-		{0x07, 0xFF, 0x00, true, true},   // CRC-8/ROHC
-		{0x9B, 0x00, 0x00, true, true},   // CRC-8/WCDMA
+		{0x07, 0x00, 0x00, false, false, "CRC-8", 8, 0xF4, 0x00},
+		{0x9B, 0xFF, 0x00, false, false, "CRC-8/CDMA2000", 8, 0xDA, 0x00},
+		{0x39, 0x00, 0x00, true, true, "CRC-8/DARC", 8, 0x15, 0x00},
+		{0xD5, 0x00, 0x00, false, false, "CRC-8/DVB-S2", 8, 0xBC, 0x00},
+		{0x1D, 0xFF, 0x00, true, true, "CRC-8/EBU", 8, 0x97, 0x00},
+		{0x1D, 0xFD, 0x00, false, false, "CRC-8/I-CODE", 8, 0x7E, 0x00},
+		{0x07, 0x00, 0x55, false, false, "CRC-8/ITU", 8, 0xA1, 0xAC},
+		{0x31, 0x00, 0x00, true, true, "CRC-8/MAXIM", 8, 0xA1, 0x00},
+		{0x07, 0xFF, 0x00, true, true, "CRC-8/ROHC", 8, 0xD0, 0x00},
+		{0x9B, 0x00, 0x00, true, true, "CRC-8/WCDMA", 8, 0x25, 0x00},
+	}
+	Crc12Configs = []Config{
+		{0xF13, 0xFFF, 0x000, false, false, "CRC-12/CDMA2000", 12, 0xD4D, 0x000},
+		{0x80F, 0x000, 0x000, false, false, "CRC-12/DECT", 12, 0xF5B, 0x000},
+		{0xD31, 0x000, 0xFFF, false, false, "CRC-12/GSM", 12, 0xB34, 0x178},
+		{0x80F, 0x000, 0x000, false, true, "CRC-12/UMTS", 12, 0xDAF, 0x000},
+	}
+	Crc15Configs = []Config{
+		{0x4599, 0x0000, 0x0000, false, false, "CRC-15/CAN", 15, 0x059E, 0x0000},
+		{0x6815, 0x0000, 0x0001, false, false, "CRC-15/MPT1327", 15, 0x2566, 0x6815},
 	}
 	Crc16Configs = []Config{
-		{0x1021, 0xFFFF, 0x0000, false, false}, // CRC-16/CCITT-FALSE
-		{0x8005, 0x0000, 0x0000, true, true},   // CRC-16/ARC
-		{0x1021, 0x1D0F, 0x0000, false, false}, // CRC-16/AUG-CCITT
-		{0x8005, 0x0000, 0x0000, false, false}, // CRC-16/BUYPASS
-		{0xC867, 0xFFFF, 0x0000, false, false}, // CRC-16/CDMA2000
-		{0x8005, 0x800D, 0x0000, false, false}, // CRC-16/DDS-110
-		{0x0589, 0x0000, 0x0001, false, false}, // CRC-16/DECT-R
-		{0x0589, 0x0000, 0x0000, false, false}, // CRC-16/DECT-X
-		{0x3D65, 0x0000, 0xFFFF, true, true},   // CRC-16/DNP
-		{0x3D65, 0x0000, 0xFFFF, false, false}, // CRC-16/EN-13757
-		{0x1021, 0xFFFF, 0xFFFF, false, false}, // CRC-16/GENIBUS
-		{0x8005, 0x0000, 0xFFFF, true, true},   // CRC-16/MAXIM
-		{0x1021, 0xFFFF, 0x0000, true, true},   // CRC-16/MCRF4XX
-		{0x8BB7, 0x0000, 0x0000, false, false}, // CRC-16/T10-DIF
-		{0xA097, 0x0000, 0x0000, false, false}, // CRC-16/TELEDISK
-		{0x8005, 0xFFFF, 0xFFFF, true, true},   // CRC-16/USB
-		{0x1021, 0x0000, 0x0000, true, true},   // CRC-16/KERMIT
-		{0x8005, 0xFFFF, 0x0000, true, true},   // CRC-16/MODBUS
-		{0x1021, 0xFFFF, 0xFFFF, true, true},   // CRC-16/X-25
-		{0x1021, 0x0000, 0x0000, false, false}, // CRC-16/XMODEM
+		{0x1021, 0xFFFF, 0x0000, false, false, "CRC-16/CCITT-FALSE", 16, 0x29B1, 0x0000},
+		{0x8005, 0x0000, 0x0000, true, true, "CRC-16/ARC", 16, 0xBB3D, 0x0000},
+		{0x1021, 0x1D0F, 0x0000, false, false, "CRC-16/AUG-CCITT", 16, 0xE5CC, 0x0000},
+		{0x8005, 0x0000, 0x0000, false, false, "CRC-16/BUYPASS", 16, 0xFEE8, 0x0000},
+		{0xC867, 0xFFFF, 0x0000, false, false, "CRC-16/CDMA2000", 16, 0x4C06, 0x0000},
+		{0x8005, 0x800D, 0x0000, false, false, "CRC-16/DDS-110", 16, 0x9ECF, 0x0000},
+		{0x0589, 0x0000, 0x0001, false, false, "CRC-16/DECT-R", 16, 0x007E, 0x0589},
+		{0x0589, 0x0000, 0x0000, false, false, "CRC-16/DECT-X", 16, 0x007F, 0x0000},
+		{0x3D65, 0x0000, 0xFFFF, true, true, "CRC-16/DNP", 16, 0xEA82, 0x66C5},
+		{0x3D65, 0x0000, 0xFFFF, false, false, "CRC-16/EN-13757", 16, 0xC2B7, 0xA366},
+		{0x1021, 0xFFFF, 0xFFFF, false, false, "CRC-16/GENIBUS", 16, 0xD64E, 0x1D0F},
+		{0x8005, 0x0000, 0xFFFF, true, true, "CRC-16/MAXIM", 16, 0x44C2, 0xB001},
+		{0x1021, 0xFFFF, 0x0000, true, true, "CRC-16/MCRF4XX", 16, 0x6F91, 0x0000},
+		{0x8BB7, 0x0000, 0x0000, false, false, "CRC-16/T10-DIF", 16, 0xD0DB, 0x0000},
+		{0xA097, 0x0000, 0x0000, false, false, "CRC-16/TELEDISK", 16, 0x0FB3, 0x0000},
+		{0x8005, 0xFFFF, 0xFFFF, true, true, "CRC-16/USB", 16, 0xB4C8, 0xB001},
+		{0x1021, 0x0000, 0x0000, true, true, "CRC-16/KERMIT", 16, 0x2189, 0x0000},
+		{0x8005, 0xFFFF, 0x0000, true, true, "CRC-16/MODBUS", 16, 0x4B37, 0x0000},
+		{0x1021, 0xFFFF, 0xFFFF, true, true, "CRC-16/X-25", 16, 0x906E, 0xF0B8},
+		{0x1021, 0x0000, 0x0000, false, false, "CRC-16/XMODEM", 16, 0x31C3, 0x0000},
+	}
+	Crc24Configs = []Config{
+		{0x864CFB, 0xB704CE, 0x000000, false, false, "CRC-24/OPENPGP", 24, 0x21CF02, 0x000000},
+		{0x00065B, 0x555555, 0x000000, true, true, "CRC-24/BLE", 24, 0xC25A56, 0x000000},
+		{0x5D6DCB, 0xFEDCBA, 0x000000, false, false, "CRC-24/FLEXRAY-A", 24, 0x7979BD, 0x000000},
+		{0x5D6DCB, 0xABCDEF, 0x000000, false, false, "CRC-24/FLEXRAY-B", 24, 0x1F23B8, 0x000000},
+		{0x328B63, 0xFFFFFF, 0xFFFFFF, false, false, "CRC-24/INTERLAKEN", 24, 0xB4F3E6, 0x144E63},
+		{0x864CFB, 0x000000, 0x000000, false, false, "CRC-24/LTE-A", 24, 0xCDE703, 0x000000},
+		{0x800063, 0x000000, 0x000000, false, false, "CRC-24/LTE-B", 24, 0x23EF52, 0x000000},
 	}
 	Crc32Configs = []Config{
-		{0x04C11DB7, 0xFFFFFFFF, 0xFFFFFFFF, true, true},   // CRC-32
-		{0x04C11DB7, 0xFFFFFFFF, 0xFFFFFFFF, false, false}, // CRC-32/BZIP2
-		{0x1EDC6F41, 0xFFFFFFFF, 0xFFFFFFFF, true, true},   // CRC-32C
-		{0xA833982B, 0xFFFFFFFF, 0xFFFFFFFF, true, true},   // CRC-32D
-		{0x04C11DB7, 0xFFFFFFFF, 0x00000000, false, false}, // CRC-32/MPEG-2
-		{0x04C11DB7, 0x00000000, 0xFFFFFFFF, false, false}, // CRC-32/POSIX
-		{0x814141AB, 0x00000000, 0x00000000, false, false}, // CRC-32Q
-		{0x04C11DB7, 0xFFFFFFFF, 0x00000000, true, true},   // CRC-32/JAMCRC
-		{0x000000AF, 0x00000000, 0x00000000, false, false}, // CRC-32/XFER
+		{0x04C11DB7, 0xFFFFFFFF, 0xFFFFFFFF, true, true, "CRC-32", 32, 0xCBF43926, 0xDEBB20E3},
+		{0x04C11DB7, 0xFFFFFFFF, 0xFFFFFFFF, false, false, "CRC-32/BZIP2", 32, 0xFC891918, 0xC704DD7B},
+		{0x1EDC6F41, 0xFFFFFFFF, 0xFFFFFFFF, true, true, "CRC-32C", 32, 0xE3069283, 0xB798B438},
+		{0xA833982B, 0xFFFFFFFF, 0xFFFFFFFF, true, true, "CRC-32D", 32, 0x87315576, 0x45270551},
+		{0x04C11DB7, 0xFFFFFFFF, 0x00000000, false, false, "CRC-32/MPEG-2", 32, 0x0376E6E7, 0x00000000},
+		{0x04C11DB7, 0x00000000, 0xFFFFFFFF, false, false, "CRC-32/POSIX", 32, 0x765E7680, 0xC704DD7B},
+		{0x814141AB, 0x00000000, 0x00000000, false, false, "CRC-32Q", 32, 0x3010BF7F, 0x00000000},
+		{0x04C11DB7, 0xFFFFFFFF, 0x00000000, true, true, "CRC-32/JAMCRC", 32, 0x340BC6D9, 0x00000000},
+		{0x000000AF, 0x00000000, 0x00000000, false, false, "CRC-32/XFER", 32, 0xBD0BE338, 0x00000000},
+	}
+	Crc40Configs = []Config{
+		{0x0004820009, 0x0000000000, 0xFFFFFFFFFF, false, false, "CRC-40/GSM", 40, 0xD4164FC646, 0xC4FF8071FF},
+	}
+	Crc64Configs = []Config{
+		{0x42F0E1EBA9EA3693, 0x0000000000000000, 0x0000000000000000, false, false, "CRC-64/ECMA-182", 64, 0x6C40DF5F0B497347, 0x0000000000000000},
+		{0x000000000000001B, 0xFFFFFFFFFFFFFFFF, 0xFFFFFFFFFFFFFFFF, true, true, "CRC-64/GO-ISO", 64, 0xB90956C775A41001, 0x5300000000000000},
+		{0x259C84CBA6426349, 0xFFFFFFFFFFFFFFFF, 0x0000000000000000, true, true, "CRC-64/MS", 64, 0x75D4B74F024ECEEA, 0x0000000000000000},
+		{0xAD93D23594C935A9, 0x0000000000000000, 0x0000000000000000, true, true, "CRC-64/REDIS", 64, 0xE9C6D914C4B8D9CA, 0x0000000000000000},
+		{0x42F0E1EBA9EA3693, 0xFFFFFFFFFFFFFFFF, 0xFFFFFFFFFFFFFFFF, false, false, "CRC-64/WE", 64, 0x62EC59E3F1A4F00A, 0xFCACBEBD5931A992},
+		{0x42F0E1EBA9EA3693, 0xFFFFFFFFFFFFFFFF, 0xFFFFFFFFFFFFFFFF, true, true, "CRC-64/XZ", 64, 0x995DC9BBDF1939FA, 0x49958C9ABD7D353F},
 	}
 )
 
+// Default indexes used by Crc8, Crc16, Crc32 and the functions based on them.
+// They are shared by every user of the package, code with a fixed wire format
+// should use a Crc instead.
 var (
 	DefaultCrc8ConfigIndex  = 0
 	DefaultCrc16ConfigIndex = 17
 	DefaultCrc32ConfigIndex = 0
 )
 
-var (
-	crc8Tables  = make([][]byte, len(Crc8Configs))
-	crc16Tables = make([][]uint16, len(Crc16Configs))
-	crc32Tables = make([][]uint32, len(Crc32Configs))
-)
+// crcTableKey holds the parameters a table depends on, so configs sharing
+// the same polynomial also share the table
+type crcTableKey struct {
+	width uint
+	poly  uint64
+	refin bool
+}
+
+type crcTableEntry struct {
+	once  sync.Once
+	table *crcSlicingTable
+}
+
+// crcTableCache caches the tables of the engine, it is safe to use by
+// multiple goroutines
+var crcTableCache sync.Map
+
+func crcTable(config Config) *crcSlicingTable {
+	key := crcTableKey{
+		config.Width, config.Polynomial & config.mask(), config.ReflectIn}
+	v, ok := crcTableCache.Load(key)
+	if !ok {
+		v, _ = crcTableCache.LoadOrStore(key, &crcTableEntry{})
+	}
+	entry := v.(*crcTableEntry)
+	entry.once.Do(func() {
+		entry.table = makeCrcSlicingTable(config)
+	})
+	return entry.table
+}
 
 type InvalidIndexError int
 
@@ -83,6 +166,206 @@ func (e InvalidIndexError) Error() string {
 	return fmt.Sprintf("core/algorithm/crc: invalid index: %d", int(e))
 }
 
+type InvalidWidthError uint
+
+func (e InvalidWidthError) Error() string {
+	return fmt.Sprintf("core/algorithm/crc: invalid width: %d", uint(e))
+}
+
+// Validate reports whether the config can be used by the CRC engine
+func (c Config) Validate() error {
+	if c.Width < 1 || c.Width > 64 {
+		return InvalidWidthError(c.Width)
+	}
+	return nil
+}
+
+func (c Config) mask() uint64 {
+	return ^uint64(0) >> (64 - c.Width)
+}
+
+func reflectBits(v uint64, width uint) uint64 {
+	return bits.Reverse64(v) >> (64 - width)
+}
+
+// MakeCrcTable builds the lookup table used by CrcImpl. For a reflected input
+// the table works on a reflected register, otherwise the register is aligned
+// to the top of an uint64, so any width from 1 to 64 shares the same code.
+func MakeCrcTable(config Config) []uint64 {
+	tbl := make([]uint64, 256, 256)
+	if config.ReflectIn {
+		poly := reflectBits(config.Polynomial&config.mask(), config.Width)
+		for i := 0; i < 256; i++ {
+			crc := uint64(i)
+			for j := 0; j < 8; j++ {
+				if crc&1 > 0 {
+					crc = (crc >> 1) ^ poly
+				} else {
+					crc >>= 1
+				}
+			}
+			tbl[i] = crc
+		}
+		return tbl
+	}
+	poly := config.Polynomial << (64 - config.Width)
+	for i := 0; i < 256; i++ {
+		crc := uint64(i) << 56
+		for j := 0; j < 8; j++ {
+			if crc&0x8000000000000000 > 0 {
+				crc = (crc << 1) ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+		tbl[i] = crc
+	}
+	return tbl
+}
+
+// loadRegister converts a CRC value to the register layout of the engine
+func loadRegister(v uint64, c Config) uint64 {
+	v &= c.mask()
+	if c.ReflectIn {
+		return reflectBits(v, c.Width)
+	}
+	return v << (64 - c.Width)
+}
+
+func updateRegister(reg uint64, data []byte, table []uint64,
+	refin bool) uint64 {
+	if refin {
+		for _, d := range data {
+			reg = (reg >> 8) ^ table[byte(reg)^d]
+		}
+	} else {
+		for _, d := range data {
+			reg = (reg << 8) ^ table[byte(reg>>56)^d]
+		}
+	}
+	return reg
+}
+
+// finalRegister converts the register of the engine to the CRC value
+func finalRegister(reg uint64, c Config) uint64 {
+	var crc uint64
+	if c.ReflectIn {
+		crc = reg
+	} else {
+		crc = reg >> (64 - c.Width)
+	}
+	if c.ReflectIn != c.ReflectOut {
+		crc = reflectBits(crc, c.Width)
+	}
+	return (crc ^ c.XorResult) & c.mask()
+}
+
+// resumeRegister is the inverse of finalRegister
+func resumeRegister(crc uint64, c Config) uint64 {
+	crc = (crc ^ c.XorResult) & c.mask()
+	if c.ReflectOut {
+		crc = reflectBits(crc, c.Width)
+	}
+	return loadRegister(crc, c)
+}
+
+// CrcImpl calculates the CRC of data, table must be made by MakeCrcTable with
+// the same config
+func CrcImpl(data []byte, table []uint64, config Config) uint64 {
+	reg := loadRegister(config.InitValue, config)
+	reg = updateRegister(reg, data, table, config.ReflectIn)
+	return finalRegister(reg, config)
+}
+
+// CrcContinueImpl continues the calculation of a CRC, prev is the CRC of the
+// data before
+func CrcContinueImpl(data []byte, table []uint64, config Config,
+	prev uint64) uint64 {
+	reg := resumeRegister(prev, config)
+	reg = updateRegister(reg, data, table, config.ReflectIn)
+	return finalRegister(reg, config)
+}
+
+// crcUpdate processes data from the register reg with the cached tables
+func crcUpdate(data []byte, config Config, reg uint64) uint64 {
+	return finalRegister(crcTable(config).update(reg, data), config)
+}
+
+// CrcWithConfig calculates the CRC of data with any config, the tables are
+// built on first use and shared by all goroutines. Data is processed 8 bytes
+// at a time, or by hash/crc32 for the polynomials it supports.
+func CrcWithConfig(data []byte, config Config) (uint64, error) {
+	if err := config.Validate(); err != nil {
+		return 0, err
+	}
+	return crcUpdate(data, config, loadRegister(config.InitValue, config)), nil
+}
+
+func CrcContinueWithConfig(data []byte, prev uint64,
+	config Config) (uint64, error) {
+	if err := config.Validate(); err != nil {
+		return 0, err
+	}
+	return crcUpdate(data, config, resumeRegister(prev, config)), nil
+}
+
+// predefinedConfig returns configs[index], a zero Width means the default
+// width of the list, so configs appended without Width keep working
+func predefinedConfig(configs []Config, index int,
+	width uint) (Config, error) {
+	if index < 0 || index >= len(configs) {
+		return Config{}, InvalidIndexError(index)
+	}
+	config := configs[index]
+	if config.Width == 0 {
+		config.Width = width
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+func crcPredefined(data []byte, configs []Config, index int,
+	width uint) (uint64, error) {
+	config, err := predefinedConfig(configs, index, width)
+	if err != nil {
+		return 0, err
+	}
+	return crcUpdate(data, config, loadRegister(config.InitValue, config)), nil
+}
+
+func crcContinuePredefined(data []byte, prev uint64, configs []Config,
+	index int, width uint) (uint64, error) {
+	config, err := predefinedConfig(configs, index, width)
+	if err != nil {
+		return 0, err
+	}
+	return crcUpdate(data, config, resumeRegister(prev, config)), nil
+}
+
+// appendCrcPredefined appends the CRC of configs[index] in little-endian, it
+// panics on invalid index as Crc8/Crc16/Crc32 do
+func appendCrcPredefined(data []byte, configs []Config, index int,
+	width uint) []byte {
+	config, err := predefinedConfig(configs, index, width)
+	if err != nil {
+		panic(fmt.Sprintf("CRC%d fail", width))
+	}
+	data, _ = AppendCrcWithConfig(data, config, binary.LittleEndian)
+	return data
+}
+
+func verifyCrcPredefined(data []byte, configs []Config, index int,
+	width uint) bool {
+	config, err := predefinedConfig(configs, index, width)
+	if err != nil {
+		panic(fmt.Sprintf("CRC%d fail", width))
+	}
+	ok, _ := VerifyCrcWithConfig(data, config, binary.LittleEndian)
+	return ok
+}
+
 func MakeCrc8Table(poly byte) []byte {
 	tbl := make([]byte, 256, 256)
 	for i := 0; i < 256; i++ {
@@ -114,29 +397,10 @@ func Crc8Impl(data []byte, table []byte, init byte,
 	return crc ^ xorout
 }
 
-func checkCrc8ConfigIndex(index int) bool {
-	if index >= len(Crc8Configs) {
-		return false
-	}
-	for len(crc8Tables) <= index {
-		crc8Tables = append(crc8Tables, nil)
-	}
-	if crc8Tables[index] == nil {
-		crc8Tables[index] = MakeCrc8Table(
-			byte(Crc8Configs[index].Polynomial))
-	}
-	return true
-}
-
 func Crc8Predefined(data []byte, configIndex int) (byte, error) {
-	if !checkCrc8ConfigIndex(configIndex) {
-		return 0, InvalidIndexError(configIndex)
-	}
-	return Crc8Impl(data, crc8Tables[configIndex],
-		byte(Crc8Configs[configIndex].InitValue),
-		Crc8Configs[configIndex].ReflectIn,
-		Crc8Configs[configIndex].ReflectOut,
-		byte(Crc8Configs[configIndex].XorResult)), nil
+	crc, err := crcPredefined(
+		data, Crc8Configs, configIndex, 8)
+	return byte(crc), err
 }
 
 func Crc8(data []byte) byte {
@@ -147,29 +411,23 @@ func Crc8(data []byte) byte {
 	return crc
 }
 
+func Crc8ContinuePredefined(data []byte, prev byte,
+	configIndex int) (byte, error) {
+	crc, err := crcContinuePredefined(data, uint64(prev),
+		Crc8Configs, configIndex, 8)
+	return byte(crc), err
+}
+
 func Crc8Continue(data []byte, prev byte) (byte, error) {
-	configIndex := DefaultCrc8ConfigIndex
-	if !checkCrc8ConfigIndex(configIndex) {
-		return 0, InvalidIndexError(configIndex)
-	}
-	if Crc8Configs[configIndex].ReflectOut {
-		prev = bits.Reverse8(prev)
-	}
-	prev ^= byte(Crc8Configs[configIndex].XorResult)
-	return Crc8Impl(data, crc8Tables[configIndex],
-		prev,
-		Crc8Configs[configIndex].ReflectIn,
-		Crc8Configs[configIndex].ReflectOut,
-		byte(Crc8Configs[configIndex].XorResult)), nil
+	return Crc8ContinuePredefined(data, prev, DefaultCrc8ConfigIndex)
 }
 
 func AppendCrc8(data []byte) []byte {
-	crc := Crc8(data)
-	return append(data, crc)
+	return appendCrcPredefined(data, Crc8Configs, DefaultCrc8ConfigIndex, 8)
 }
 
 func VerifyCrc8(data []byte) bool {
-	return Crc8(data) == 0
+	return verifyCrcPredefined(data, Crc8Configs, DefaultCrc8ConfigIndex, 8)
 }
 
 func MakeCrc16Table(poly uint16) []uint16 {
@@ -203,29 +461,10 @@ func Crc16Impl(data []byte, table []uint16, init uint16,
 	return crc ^ xorout
 }
 
-func checkCrc16ConfigIndex(index int) bool {
-	if index >= len(Crc16Configs) {
-		return false
-	}
-	for len(crc16Tables) <= index {
-		crc16Tables = append(crc16Tables, nil)
-	}
-	if crc16Tables[index] == nil {
-		crc16Tables[index] = MakeCrc16Table(
-			uint16(Crc16Configs[index].Polynomial))
-	}
-	return true
-}
-
 func Crc16Predefined(data []byte, configIndex int) (uint16, error) {
-	if !checkCrc16ConfigIndex(configIndex) {
-		return 0, InvalidIndexError(configIndex)
-	}
-	return Crc16Impl(data, crc16Tables[configIndex],
-		uint16(Crc16Configs[configIndex].InitValue),
-		Crc16Configs[configIndex].ReflectIn,
-		Crc16Configs[configIndex].ReflectOut,
-		uint16(Crc16Configs[configIndex].XorResult)), nil
+	crc, err := crcPredefined(
+		data, Crc16Configs, configIndex, 16)
+	return uint16(crc), err
 }
 
 func Crc16(data []byte) uint16 {
@@ -236,29 +475,24 @@ func Crc16(data []byte) uint16 {
 	return crc
 }
 
+func Crc16ContinuePredefined(data []byte, prev uint16,
+	configIndex int) (uint16, error) {
+	crc, err := crcContinuePredefined(data, uint64(prev),
+		Crc16Configs, configIndex, 16)
+	return uint16(crc), err
+}
+
 func Crc16Continue(data []byte, prev uint16) (uint16, error) {
-	configIndex := DefaultCrc16ConfigIndex
-	if !checkCrc16ConfigIndex(configIndex) {
-		return 0, InvalidIndexError(configIndex)
-	}
-	if Crc16Configs[configIndex].ReflectOut {
-		prev = bits.Reverse16(prev)
-	}
-	prev ^= uint16(Crc16Configs[configIndex].XorResult)
-	return Crc16Impl(data, crc16Tables[configIndex],
-		prev,
-		Crc16Configs[configIndex].ReflectIn,
-		Crc16Configs[configIndex].ReflectOut,
-		uint16(Crc16Configs[configIndex].XorResult)), nil
+	return Crc16ContinuePredefined(data, prev, DefaultCrc16ConfigIndex)
 }
 
+// AppendCrc16 appends the CRC of the default config in little-endian
 func AppendCrc16(data []byte) []byte {
-	crc := Crc16(data)
-	return append(data, []byte{byte(crc), byte(crc >> 8)}...)
+	return appendCrcPredefined(data, Crc16Configs, DefaultCrc16ConfigIndex, 16)
 }
 
 func VerifyCrc16(data []byte) bool {
-	return Crc16(data) == 0
+	return verifyCrcPredefined(data, Crc16Configs, DefaultCrc16ConfigIndex, 16)
 }
 
 func MakeCrc32Table(poly uint32) []uint32 {
@@ -292,29 +526,10 @@ func Crc32Impl(data []byte, table []uint32, init uint32,
 	return crc ^ xorout
 }
 
-func checkCrc32ConfigIndex(index int) bool {
-	if index >= len(Crc32Configs) {
-		return false
-	}
-	for len(crc32Tables) <= index {
-		crc32Tables = append(crc32Tables, nil)
-	}
-	if crc32Tables[index] == nil {
-		crc32Tables[index] = MakeCrc32Table(
-			uint32(Crc32Configs[index].Polynomial))
-	}
-	return true
-}
-
 func Crc32Predefined(data []byte, configIndex int) (uint32, error) {
-	if !checkCrc32ConfigIndex(configIndex) {
-		return 0, InvalidIndexError(configIndex)
-	}
-	return Crc32Impl(data, crc32Tables[configIndex],
-		uint32(Crc32Configs[configIndex].InitValue),
-		Crc32Configs[configIndex].ReflectIn,
-		Crc32Configs[configIndex].ReflectOut,
-		uint32(Crc32Configs[configIndex].XorResult)), nil
+	crc, err := crcPredefined(
+		data, Crc32Configs, configIndex, 32)
+	return uint32(crc), err
 }
 
 func Crc32(data []byte) uint32 {
@@ -325,28 +540,22 @@ func Crc32(data []byte) uint32 {
 	return crc
 }
 
+func Crc32ContinuePredefined(data []byte, prev uint32,
+	configIndex int) (uint32, error) {
+	crc, err := crcContinuePredefined(data, uint64(prev),
+		Crc32Configs, configIndex, 32)
+	return uint32(crc), err
+}
+
 func Crc32Continue(data []byte, prev uint32) (uint32, error) {
-	configIndex := DefaultCrc32ConfigIndex
-	if !checkCrc32ConfigIndex(configIndex) {
-		return 0, InvalidIndexError(configIndex)
-	}
-	if Crc32Configs[configIndex].ReflectOut {
-		prev = bits.Reverse32(prev)
-	}
-	prev ^= uint32(Crc32Configs[configIndex].XorResult)
-	return Crc32Impl(data, crc32Tables[configIndex],
-		prev,
-		Crc32Configs[configIndex].ReflectIn,
-		Crc32Configs[configIndex].ReflectOut,
-		uint32(Crc32Configs[configIndex].XorResult)), nil
+	return Crc32ContinuePredefined(data, prev, DefaultCrc32ConfigIndex)
 }
 
+// AppendCrc32 appends the CRC of the default config in little-endian
 func AppendCrc32(data []byte) []byte {
-	crc := Crc32(data)
-	return append(data,
-		[]byte{byte(crc), byte(crc >> 8), byte(crc >> 16), byte(crc >> 24)}...)
+	return appendCrcPredefined(data, Crc32Configs, DefaultCrc32ConfigIndex, 32)
 }
 
 func VerifyCrc32(data []byte) bool {
-	return Crc32(data) == 0x2144DF1C
+	return verifyCrcPredefined(data, Crc32Configs, DefaultCrc32ConfigIndex, 32)
 }
@@ -0,0 +1,146 @@
+package algorithm
+
+import (
+	"encoding/binary"
+)
+
+// crcResidue calculates the residue of c, see Config
+func crcResidue(c Config) uint64 {
+	reg := c.XorResult & c.mask()
+	if c.ReflectOut {
+		reg = reflectBits(reg, c.Width)
+	}
+	reg = mulMod(reg, xPowMod(uint64(c.Width), c), c)
+	if c.ReflectOut {
+		reg = reflectBits(reg, c.Width)
+	}
+	return reg
+}
+
+// CrcResidue calculates the residue of config from its parameters, the
+// Residue field of config is ignored
+func CrcResidue(config Config) (uint64, error) {
+	if err := config.Validate(); err != nil {
+		return 0, err
+	}
+	return crcResidue(config), nil
+}
+
+// crcNaturalOrder returns the byte order in which the CRC is shifted out by
+// the hardware, only in this order the residue of a codeword is constant
+func crcNaturalOrder(c Config) binary.ByteOrder {
+	if c.ReflectOut {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// hasConstantResidue reports whether the CRC of every codeword of c in order
+// is the residue. The CRC bytes must be read with the same reflection as the
+// message, so ReflectIn and ReflectOut must be the same.
+func hasConstantResidue(c Config, order binary.ByteOrder) bool {
+	return c.Width%8 == 0 && c.ReflectIn == c.ReflectOut &&
+		order == crcNaturalOrder(c)
+}
+
+// verifyCrc checks the CRC at the end of data, checksum calculates the CRC
+// of c and residue is the CRC of a codeword
+func verifyCrc(data []byte, c Config, order binary.ByteOrder, residue uint64,
+	checksum func([]byte) uint64) bool {
+	n := crcSize(c)
+	if len(data) < n {
+		return false
+	}
+	if hasConstantResidue(c, order) {
+		return checksum(data) == residue
+	}
+	crc := checksum(data[:len(data)-n])
+	return crc == getCrc(data[len(data)-n:], n, order)&c.mask()
+}
+
+// crcSize is the number of bytes used to transmit the CRC
+func crcSize(c Config) int {
+	return int(c.Width+7) / 8
+}
+
+// putCrc writes the low n bytes of crc to buf in the given order. For the
+// sizes without a method in binary.ByteOrder, the bytes are picked from the
+// 64 bits encoding, so any permutation of bytes is supported.
+func putCrc(buf []byte, crc uint64, n int, order binary.ByteOrder) {
+	switch n {
+	case 1:
+		buf[0] = byte(crc)
+	case 2:
+		order.PutUint16(buf, uint16(crc))
+	case 4:
+		order.PutUint32(buf, uint32(crc))
+	case 8:
+		order.PutUint64(buf, crc)
+	default:
+		var value, probe [8]byte
+		order.PutUint64(value[:], crc)
+		order.PutUint64(probe[:], ^uint64(0)>>(64-uint(n)*8))
+		j := 0
+		for i := range probe {
+			if probe[i] != 0 {
+				buf[j] = value[i]
+				j++
+			}
+		}
+	}
+}
+
+// getCrc is the inverse of putCrc
+func getCrc(buf []byte, n int, order binary.ByteOrder) uint64 {
+	switch n {
+	case 1:
+		return uint64(buf[0])
+	case 2:
+		return uint64(order.Uint16(buf))
+	case 4:
+		return uint64(order.Uint32(buf))
+	case 8:
+		return order.Uint64(buf)
+	}
+	var value, probe [8]byte
+	order.PutUint64(probe[:], ^uint64(0)>>(64-uint(n)*8))
+	j := 0
+	for i := range probe {
+		if probe[i] != 0 {
+			value[i] = buf[j]
+			j++
+		}
+	}
+	return order.Uint64(value[:])
+}
+
+// AppendCrcWithConfig appends the CRC of data in (Width+7)/8 bytes with the
+// given byte order
+func AppendCrcWithConfig(data []byte, config Config,
+	order binary.ByteOrder) ([]byte, error) {
+	crc, err := CrcWithConfig(data, config)
+	if err != nil {
+		return data, err
+	}
+	var buf [8]byte
+	n := crcSize(config)
+	putCrc(buf[:], crc, n, order)
+	return append(data, buf[:n]...), nil
+}
+
+// VerifyCrcWithConfig reports whether data ends with the CRC of the bytes
+// before it, as appended by AppendCrcWithConfig. When the CRC is sent in its
+// natural order (little-endian if ReflectOut, otherwise big-endian) and
+// ReflectIn equals ReflectOut, the whole codeword is checked against the
+// residue in one pass.
+func VerifyCrcWithConfig(data []byte, config Config,
+	order binary.ByteOrder) (bool, error) {
+	if err := config.Validate(); err != nil {
+		return false, err
+	}
+	residue := (crcResidue(config) ^ config.XorResult) & config.mask()
+	return verifyCrc(data, config, order, residue, func(b []byte) uint64 {
+		crc, _ := CrcWithConfig(b, config)
+		return crc
+	}), nil
+}
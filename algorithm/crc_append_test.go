@@ -0,0 +1,114 @@
+package algorithm
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCrcResidueExported(t *testing.T) {
+	assert := assert.New(t)
+	for _, configs := range allConfigs {
+		for _, c := range configs {
+			residue, err := CrcResidue(c)
+			assert.Nil(err)
+			assert.Equal(residue, c.Residue, c.Name)
+		}
+	}
+	_, err := CrcResidue(Config{})
+	assert.Equal(err, InvalidWidthError(0))
+}
+
+func TestPutGetCrc(t *testing.T) {
+	assert := assert.New(t)
+	var buf [8]byte
+	for n := 1; n <= 8; n++ {
+		value := uint64(0x0102030405060708) >> (64 - uint(n)*8)
+		for _, order := range []binary.ByteOrder{
+			binary.BigEndian, binary.LittleEndian} {
+			putCrc(buf[:], value, n, order)
+			assert.Equal(getCrc(buf[:], n, order), value)
+		}
+		putCrc(buf[:], value, n, binary.BigEndian)
+		assert.EqualValues(1, buf[0])
+		assert.EqualValues(n, buf[n-1])
+		putCrc(buf[:], value, n, binary.LittleEndian)
+		assert.EqualValues(n, buf[0])
+		assert.EqualValues(1, buf[n-1])
+	}
+}
+
+func TestAppendVerifyCrcWithConfig(t *testing.T) {
+	assert := assert.New(t)
+	orders := []binary.ByteOrder{binary.BigEndian, binary.LittleEndian}
+	for _, configs := range allConfigs {
+		for _, c := range configs {
+			n := int(c.Width+7) / 8
+			for _, order := range orders {
+				data, err := AppendCrcWithConfig(
+					append([]byte(nil), checkData...), c, order)
+				assert.Nil(err)
+				assert.Equal(len(data), len(checkData)+n)
+				assert.Equal(getCrc(data[len(checkData):], n, order), c.Check,
+					c.Name)
+				ok, err := VerifyCrcWithConfig(data, c, order)
+				assert.True(ok, c.Name)
+				assert.Nil(err)
+				data[0] ^= 0x01
+				ok, _ = VerifyCrcWithConfig(data, c, order)
+				assert.False(ok, c.Name)
+			}
+		}
+	}
+	ok, err := VerifyCrcWithConfig([]byte{0x01}, Crc32Configs[0],
+		binary.LittleEndian)
+	assert.False(ok)
+	assert.Nil(err)
+	_, err = AppendCrcWithConfig(nil, Config{Width: 65}, binary.BigEndian)
+	assert.Equal(err, InvalidWidthError(65))
+	_, err = VerifyCrcWithConfig(nil, Config{}, binary.BigEndian)
+	assert.Equal(err, InvalidWidthError(0))
+}
+
+func TestVerifyCrcNonDefault(t *testing.T) {
+	assert := assert.New(t)
+	bzip2, _ := CrcByName("CRC-32/BZIP2")
+	data, _ := AppendCrcWithConfig(
+		append([]byte(nil), checkData...), bzip2, binary.BigEndian)
+	assert.Equal(data[len(checkData):], []byte{0xFC, 0x89, 0x19, 0x18})
+	ok, _ := VerifyCrcWithConfig(data, bzip2, binary.BigEndian)
+	assert.True(ok)
+	// the same config in the other byte order has no constant residue
+	data, _ = AppendCrcWithConfig(
+		append([]byte(nil), checkData...), bzip2, binary.LittleEndian)
+	ok, _ = VerifyCrcWithConfig(data, bzip2, binary.LittleEndian)
+	assert.True(ok)
+}
+
+// mixedReflectionConfigs have ReflectIn different from ReflectOut, so the
+// residue of a codeword is not constant
+var mixedReflectionConfigs = []Config{
+	{Width: 16, Polynomial: 0x1021, InitValue: 0xFFFF, ReflectOut: true},
+	{Width: 16, Polynomial: 0x8005, ReflectIn: true, XorResult: 0xFFFF},
+	{Width: 32, Polynomial: 0x04C11DB7, InitValue: 0xFFFFFFFF,
+		ReflectIn: true, XorResult: 0xFFFFFFFF},
+	{Width: 8, Polynomial: 0x07, ReflectOut: true},
+}
+
+func TestAppendVerifyCrcMixedReflection(t *testing.T) {
+	assert := assert.New(t)
+	orders := []binary.ByteOrder{binary.BigEndian, binary.LittleEndian}
+	for _, c := range mixedReflectionConfigs {
+		for _, order := range orders {
+			data, err := AppendCrcWithConfig(
+				append([]byte(nil), checkData...), c, order)
+			assert.Nil(err)
+			ok, err := VerifyCrcWithConfig(data, c, order)
+			assert.True(ok, "%+v %v", c, order)
+			assert.Nil(err)
+			data[len(data)-1] ^= 0x01
+			ok, _ = VerifyCrcWithConfig(data, c, order)
+			assert.False(ok, "%+v %v", c, order)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package algorithm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// crcAliases lists other names of the predefined configs, most of them come
+// from the RevEng catalogue
+var crcAliases = map[string][]string{
+	"CRC-5/EPC-C1G2": {"CRC-5/EPC"},
+	"CRC-5/G-704":    {"CRC-5/ITU"},
+	"CRC-7/MMC":      {"CRC-7"},
+	"CRC-8":          {"CRC-8/SMBUS"},
+	"CRC-8/EBU":      {"CRC-8/AES", "CRC-8/TECH-3250"},
+	"CRC-8/ITU":      {"CRC-8/I-432-1"},
+	"CRC-8/MAXIM":    {"CRC-8/MAXIM-DOW", "DOW-CRC"},
+	"CRC-12/DECT":    {"X-CRC-12"},
+	"CRC-12/UMTS":    {"CRC-12/3GPP"},
+	"CRC-15/CAN":     {"CRC-15"},
+	"CRC-16/CCITT-FALSE": {
+		"CRC-16/IBM-3740", "CRC-16/AUTOSAR"},
+	"CRC-16/ARC": {
+		"ARC", "CRC-16", "CRC-16/LHA", "CRC-IBM"},
+	"CRC-16/AUG-CCITT": {"CRC-16/SPI-FUJITSU"},
+	"CRC-16/BUYPASS":   {"CRC-16/UMTS", "CRC-16/VERIFONE"},
+	"CRC-16/DECT-R":    {"R-CRC-16"},
+	"CRC-16/DECT-X":    {"X-CRC-16"},
+	"CRC-16/GENIBUS": {
+		"CRC-16/DARC", "CRC-16/EPC", "CRC-16/EPC-C1G2", "CRC-16/I-CODE"},
+	"CRC-16/MAXIM": {"CRC-16/MAXIM-DOW"},
+	"CRC-16/KERMIT": {
+		"CRC-16/CCITT", "CRC-16/CCITT-TRUE", "CRC-16/V-41-LSB", "CRC-CCITT",
+		"KERMIT"},
+	"CRC-16/MODBUS": {"MODBUS"},
+	"CRC-16/X-25": {
+		"X-25", "CRC-16/IBM-SDLC", "CRC-16/ISO-HDLC",
+		"CRC-16/ISO-IEC-14443-3-B", "CRC-B"},
+	"CRC-16/XMODEM": {
+		"XMODEM", "ZMODEM", "CRC-16/ACORN", "CRC-16/LTE", "CRC-16/V-41-MSB"},
+	"CRC-24/OPENPGP": {"CRC-24"},
+	"CRC-32": {
+		"CRC-32/ISO-HDLC", "CRC-32/ADCCP", "CRC-32/V-42", "CRC-32/XZ",
+		"PKZIP"},
+	"CRC-32/BZIP2": {"CRC-32/AAL5", "CRC-32/DECT-B", "B-CRC-32"},
+	"CRC-32C": {
+		"CRC-32/ISCSI", "CRC-32/BASE91-C", "CRC-32/CASTAGNOLI",
+		"CRC-32/INTERLAKEN"},
+	"CRC-32D":         {"CRC-32/BASE91-D"},
+	"CRC-32/POSIX":    {"CRC-32/CKSUM", "CKSUM"},
+	"CRC-32Q":         {"CRC-32/AIXM"},
+	"CRC-32/JAMCRC":   {"JAMCRC"},
+	"CRC-32/XFER":     {"XFER"},
+	"CRC-64/ECMA-182": {"CRC-64"},
+	"CRC-64/XZ":       {"CRC-64/GO-ECMA"},
+}
+
+type UnknownNameError string
+
+func (e UnknownNameError) Error() string {
+	return fmt.Sprintf("core/algorithm/crc: unknown name: %s", string(e))
+}
+
+func crcConfigLists() [][]Config {
+	return [][]Config{
+		Crc5Configs, Crc7Configs, Crc8Configs, Crc12Configs, Crc15Configs,
+		Crc16Configs, Crc24Configs, Crc32Configs, Crc40Configs, Crc64Configs,
+	}
+}
+
+// CrcCatalogue returns all predefined configs ordered by width, configs
+// appended to the CrcXXConfigs lists are included
+func CrcCatalogue() []Config {
+	var result []Config
+	for _, configs := range crcConfigLists() {
+		result = append(result, configs...)
+	}
+	return result
+}
+
+// CrcNames returns the canonical names of all configs in CrcCatalogue
+func CrcNames() []string {
+	var result []string
+	for _, config := range CrcCatalogue() {
+		if config.Name != "" {
+			result = append(result, config.Name)
+		}
+	}
+	return result
+}
+
+// CrcAliases returns the other names of the config named name
+func CrcAliases(name string) []string {
+	config, err := CrcByName(name)
+	if err != nil {
+		return nil
+	}
+	return append([]string(nil), crcAliases[config.Name]...)
+}
+
+func isCrcName(config Config, name string) bool {
+	if strings.EqualFold(config.Name, name) {
+		return true
+	}
+	for _, alias := range crcAliases[config.Name] {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// CrcByName finds a config by its canonical name or an alias, case
+// insensitive, e.g. "CRC-16/MODBUS", "X-25" or "crc-32c"
+func CrcByName(name string) (Config, error) {
+	name = strings.TrimSpace(name)
+	if name != "" {
+		for _, config := range CrcCatalogue() {
+			if isCrcName(config, name) {
+				return config, nil
+			}
+		}
+	}
+	return Config{}, UnknownNameError(name)
+}
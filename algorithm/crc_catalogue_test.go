@@ -0,0 +1,78 @@
+package algorithm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCrcByName(t *testing.T) {
+	assert := assert.New(t)
+	c, err := CrcByName("CRC-16/MODBUS")
+	if assert.Nil(err) {
+		assert.Equal(c, Crc16Configs[17])
+	}
+	c, err = CrcByName("X-25")
+	if assert.Nil(err) {
+		assert.Equal(c.Name, "CRC-16/X-25")
+		assert.EqualValues(c.Check, 0x906E)
+		assert.EqualValues(c.Residue, 0xF0B8)
+	}
+	c, err = CrcByName(" crc-32c ")
+	if assert.Nil(err) {
+		assert.Equal(c.Name, "CRC-32C")
+	}
+	c, err = CrcByName("CRC-64/GO-ECMA")
+	if assert.Nil(err) {
+		assert.Equal(c.Name, "CRC-64/XZ")
+		assert.EqualValues(c.Width, 64)
+	}
+	if _, err = CrcByName("CRC-16/UNKNOWN"); assert.NotNil(err) {
+		assert.EqualError(err, "core/algorithm/crc: unknown name: CRC-16/UNKNOWN")
+	}
+	_, err = CrcByName("")
+	assert.Equal(err, UnknownNameError(""))
+}
+
+func TestCrcNamesAreUnique(t *testing.T) {
+	assert := assert.New(t)
+	names := make(map[string]bool)
+	for _, name := range CrcNames() {
+		assert.False(names[strings.ToUpper(name)], name)
+		names[strings.ToUpper(name)] = true
+	}
+	for name, aliases := range crcAliases {
+		_, err := CrcByName(name)
+		assert.Nil(err, name)
+		for _, alias := range aliases {
+			assert.False(names[strings.ToUpper(alias)], alias)
+			names[strings.ToUpper(alias)] = true
+		}
+	}
+}
+
+func TestCrcCatalogue(t *testing.T) {
+	assert := assert.New(t)
+	catalogue := CrcCatalogue()
+	assert.True(len(catalogue) >= 65)
+	assert.Equal(catalogue[0].Name, "CRC-5/EPC-C1G2")
+	for _, c := range catalogue {
+		if c.Name == "" {
+			continue
+		}
+		found, err := CrcByName(c.Name)
+		if assert.Nil(err) {
+			assert.Equal(found, c)
+		}
+	}
+	catalogue[0].Name = "changed"
+	assert.Equal(Crc5Configs[0].Name, "CRC-5/EPC-C1G2")
+}
+
+func TestCrcAliases(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(CrcAliases("crc-16/modbus"), []string{"MODBUS"})
+	assert.Contains(CrcAliases("X-25"), "CRC-16/IBM-SDLC")
+	assert.Empty(CrcAliases("CRC-16/DNP"))
+	assert.Nil(CrcAliases("CRC-16/UNKNOWN"))
+}
@@ -0,0 +1,122 @@
+package algorithm
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallelMinChunk is the minimum bytes handled by a goroutine in
+// CrcWithConfigParallel, smaller chunks cost more than they save
+const parallelMinChunk = 64 * 1024
+
+// mulXMod returns a*x mod P, P is the polynomial of c with the x^Width term
+func mulXMod(a uint64, c Config) uint64 {
+	if a&(uint64(1)<<(c.Width-1)) > 0 {
+		return ((a << 1) ^ c.Polynomial) & c.mask()
+	}
+	return (a << 1) & c.mask()
+}
+
+// mulMod returns a*b mod P in GF(2), a and b have at most Width bits
+func mulMod(a, b uint64, c Config) uint64 {
+	var r uint64
+	for i := c.Width; i > 0; i-- {
+		r = mulXMod(r, c)
+		if (b>>(i-1))&1 > 0 {
+			r ^= a
+		}
+	}
+	return r
+}
+
+// xPowMod returns x^n mod P
+func xPowMod(n uint64, c Config) uint64 {
+	result := uint64(1)
+	base := mulXMod(1, c)
+	for ; n > 0; n >>= 1 {
+		if n&1 > 0 {
+			result = mulMod(result, base, c)
+		}
+		base = mulMod(base, base, c)
+	}
+	return result
+}
+
+// logicalRegister converts a CRC value to the register of the bitwise
+// algorithm, before reflection and final xor
+func logicalRegister(crc uint64, c Config) uint64 {
+	crc = (crc ^ c.XorResult) & c.mask()
+	if c.ReflectOut {
+		crc = reflectBits(crc, c.Width)
+	}
+	return crc
+}
+
+// CrcCombine returns the CRC of A followed by B, crcA and crcB are the CRC of
+// A and B, lenB is the length of B in bytes. It is the generalization of
+// crc32_combine in zlib for any config.
+func CrcCombine(crcA, crcB uint64, lenB int64, config Config) (uint64, error) {
+	if err := config.Validate(); err != nil {
+		return 0, err
+	}
+	if lenB <= 0 {
+		return crcA & config.mask(), nil
+	}
+	// processing B from a register r gives r*x^(8*lenB) + f(B), where f(B)
+	// is linear, and crcB tells f(B) when r is InitValue
+	init := config.InitValue & config.mask()
+	reg := logicalRegister(crcA, config) ^ init
+	reg = mulMod(reg, xPowMod(uint64(lenB)*8, config), config)
+	reg ^= logicalRegister(crcB, config)
+	if config.ReflectOut {
+		reg = reflectBits(reg, config.Width)
+	}
+	return (reg ^ config.XorResult) & config.mask(), nil
+}
+
+// CrcWithConfigParallel splits data into workers chunks, calculates the CRC
+// of them in separate goroutines and combines the results. workers <= 0
+// means runtime.NumCPU().
+func CrcWithConfigParallel(data []byte, config Config,
+	workers int) (uint64, error) {
+	if err := config.Validate(); err != nil {
+		return 0, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if max := len(data) / parallelMinChunk; workers > max {
+		workers = max
+	}
+	if workers <= 1 {
+		return CrcWithConfig(data, config)
+	}
+	chunkSize := (len(data) + workers - 1) / workers
+	crcs := make([]uint64, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			crcs[i], _ = CrcWithConfig(chunk(data, i, chunkSize), config)
+		}(i)
+	}
+	wg.Wait()
+	result := crcs[0]
+	for i := 1; i < workers; i++ {
+		result, _ = CrcCombine(
+			result, crcs[i], int64(len(chunk(data, i, chunkSize))), config)
+	}
+	return result, nil
+}
+
+func chunk(data []byte, i int, size int) []byte {
+	start, end := i*size, (i+1)*size
+	if start > len(data) {
+		start = len(data)
+	}
+	if end > len(data) {
+		end = len(data)
+	}
+	return data[start:end]
+}
@@ -0,0 +1,96 @@
+package algorithm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCrcCombineAllConfigs(t *testing.T) {
+	assert := assert.New(t)
+	data := makeRandomData(300)
+	for _, configs := range allConfigs {
+		for _, c := range configs {
+			expect, _ := CrcWithConfig(data, c)
+			for _, n := range []int{0, 1, 5, 150, 299, 300} {
+				a, _ := CrcWithConfig(data[:n], c)
+				b, _ := CrcWithConfig(data[n:], c)
+				crc, err := CrcCombine(a, b, int64(len(data)-n), c)
+				if assert.Nil(err) {
+					assert.Equal(expect, crc, "%s %d", c.Name, n)
+				}
+			}
+		}
+	}
+}
+
+func TestCrcCombineSmallWidth(t *testing.T) {
+	assert := assert.New(t)
+	data := makeRandomData(100)
+	for _, c := range []Config{
+		{Width: 1, Polynomial: 1},
+		{Width: 1, Polynomial: 1, InitValue: 1, XorResult: 1},
+		{Width: 2, Polynomial: 3, InitValue: 2, ReflectIn: true},
+		{Width: 3, Polynomial: 3, XorResult: 7, ReflectOut: true},
+	} {
+		expect, _ := CrcWithConfig(data, c)
+		a, _ := CrcWithConfig(data[:33], c)
+		b, _ := CrcWithConfig(data[33:], c)
+		crc, _ := CrcCombine(a, b, 67, c)
+		assert.Equal(expect, crc, "%+v", c)
+	}
+}
+
+func TestCrcCombinePatch(t *testing.T) {
+	assert := assert.New(t)
+	c, _ := CrcByName("CRC-16/MODBUS")
+	frame := append([]byte(nil), testData...)
+	head, _ := CrcWithConfig(frame[:10], c)
+	frame[10], frame[11] = 0x55, 0xAA
+	body, _ := CrcWithConfig(frame[10:], c)
+	crc, _ := CrcCombine(head, body, int64(len(frame)-10), c)
+	expect, _ := CrcWithConfig(frame, c)
+	assert.Equal(crc, expect)
+}
+
+func TestCrcCombineInvalid(t *testing.T) {
+	assert := assert.New(t)
+	_, err := CrcCombine(0, 0, 1, Config{})
+	assert.Equal(err, InvalidWidthError(0))
+	_, err = CrcWithConfigParallel(nil, Config{Width: 65}, 0)
+	assert.Equal(err, InvalidWidthError(65))
+}
+
+func TestCrcWithConfigParallel(t *testing.T) {
+	assert := assert.New(t)
+	for _, name := range []string{"CRC-32", "CRC-32/BZIP2", "CRC-16/MODBUS",
+		"CRC-64/XZ", "CRC-5/USB", "CRC-12/UMTS"} {
+		c, _ := CrcByName(name)
+		expect, _ := CrcWithConfig(benchData, c)
+		for _, workers := range []int{0, 1, 3, 7, 100} {
+			crc, err := CrcWithConfigParallel(benchData, c, workers)
+			if assert.Nil(err) {
+				assert.Equal(expect, crc, "%s %d", name, workers)
+			}
+		}
+		crc, _ := CrcWithConfigParallel(benchData[:parallelMinChunk*3+1], c, 4)
+		expect, _ = CrcWithConfig(benchData[:parallelMinChunk*3+1], c)
+		assert.Equal(expect, crc)
+		crc, _ = CrcWithConfigParallel(checkData, c, 4)
+		assert.Equal(c.Check, crc)
+	}
+}
+
+func BenchmarkCrcCombine(b *testing.B) {
+	c, _ := CrcByName("CRC-32/BZIP2")
+	for i := 0; i < b.N; i++ {
+		CrcCombine(0x12345678, 0x9ABCDEF0, 1<<20, c)
+	}
+}
+
+func BenchmarkCrc32BZIP2Parallel(b *testing.B) {
+	c, _ := CrcByName("CRC-32/BZIP2")
+	b.SetBytes(int64(len(benchData)))
+	for i := 0; i < b.N; i++ {
+		CrcWithConfigParallel(benchData, c, 0)
+	}
+}
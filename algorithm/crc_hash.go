@@ -0,0 +1,110 @@
+package algorithm
+
+import (
+	"hash"
+)
+
+// Hash8 is the common interface implemented by all 8-bit hash functions.
+type Hash8 interface {
+	hash.Hash
+	Sum8() uint8
+}
+
+// Hash16 is the common interface implemented by all 16-bit hash functions.
+type Hash16 interface {
+	hash.Hash
+	Sum16() uint16
+}
+
+// CrcHash is implemented by the CRC digests of any width, Sum64 returns the
+// CRC of all written data.
+type CrcHash interface {
+	hash.Hash64
+	Config() Config
+}
+
+type crcDigest struct {
+	config Config
+	table  *crcSlicingTable
+	reg    uint64
+}
+
+func newCrcDigest(config Config, maxWidth uint) (*crcDigest, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Width > maxWidth {
+		return nil, InvalidWidthError(config.Width)
+	}
+	d := &crcDigest{config: config, table: crcTable(config)}
+	d.Reset()
+	return d, nil
+}
+
+// NewCrcHash creates a hash.Hash64 computing the CRC described by config
+func NewCrcHash(config Config) (CrcHash, error) {
+	return newCrcDigest(config, 64)
+}
+
+// NewCrc8Hash creates a Hash8 for a config with width up to 8
+func NewCrc8Hash(config Config) (Hash8, error) {
+	return newCrcDigest(config, 8)
+}
+
+// NewCrc16Hash creates a Hash16 for a config with width up to 16
+func NewCrc16Hash(config Config) (Hash16, error) {
+	return newCrcDigest(config, 16)
+}
+
+// NewCrc32Hash creates a hash.Hash32 for a config with width up to 32
+func NewCrc32Hash(config Config) (hash.Hash32, error) {
+	return newCrcDigest(config, 32)
+}
+
+func (d *crcDigest) Config() Config {
+	return d.config
+}
+
+// Size is the number of bytes appended by Sum, the CRC is rounded up to
+// whole bytes.
+func (d *crcDigest) Size() int {
+	return int(d.config.Width+7) / 8
+}
+
+func (d *crcDigest) BlockSize() int {
+	return 1
+}
+
+func (d *crcDigest) Reset() {
+	d.reg = loadRegister(d.config.InitValue, d.config)
+}
+
+func (d *crcDigest) Write(p []byte) (int, error) {
+	d.reg = d.table.update(d.reg, p)
+	return len(p), nil
+}
+
+// Sum appends the CRC in big-endian order, as hash/crc32 does
+func (d *crcDigest) Sum(in []byte) []byte {
+	s := d.Sum64()
+	for i := d.Size() - 1; i >= 0; i-- {
+		in = append(in, byte(s>>(uint(i)*8)))
+	}
+	return in
+}
+
+func (d *crcDigest) Sum8() uint8 {
+	return uint8(d.Sum64())
+}
+
+func (d *crcDigest) Sum16() uint16 {
+	return uint16(d.Sum64())
+}
+
+func (d *crcDigest) Sum32() uint32 {
+	return uint32(d.Sum64())
+}
+
+func (d *crcDigest) Sum64() uint64 {
+	return finalRegister(d.reg, d.config)
+}
@@ -0,0 +1,111 @@
+package algorithm
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"hash"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+var (
+	_ hash.Hash32 = &crcDigest{}
+	_ hash.Hash64 = &crcDigest{}
+	_ Hash16      = &crcDigest{}
+	_ Hash8       = &crcDigest{}
+)
+
+func TestCrcHashAllConfigs(t *testing.T) {
+	assert := assert.New(t)
+	for _, configs := range allConfigs {
+		for _, c := range configs {
+			h, err := NewCrcHash(c)
+			if !assert.Nil(err) {
+				continue
+			}
+			assert.Equal(h.Config(), c)
+			h.Write(checkData[:3])
+			h.Write(checkData[3:])
+			assert.Equal(h.Sum64(), c.Check, "%+v", c)
+			h.Reset()
+			n, err := io.Copy(h, bytes.NewReader(testData))
+			assert.Nil(err)
+			assert.EqualValues(n, len(testData))
+			expect, _ := CrcWithConfig(testData, c)
+			assert.Equal(h.Sum64(), expect, "%+v", c)
+		}
+	}
+}
+
+func TestCrcHashSum(t *testing.T) {
+	assert := assert.New(t)
+	h, _ := NewCrc32Hash(Crc32Configs[0])
+	std := crc32.NewIEEE()
+	h.Write(testData)
+	std.Write(testData)
+	assert.Equal(h.Size(), std.Size())
+	assert.Equal(h.BlockSize(), std.BlockSize())
+	assert.Equal(h.Sum32(), std.Sum32())
+	assert.Equal(h.Sum([]byte{0xAA}), std.Sum([]byte{0xAA}))
+
+	c, _ := CrcByName("CRC-12/GSM")
+	h12, _ := NewCrc16Hash(c)
+	h12.Write(checkData)
+	assert.Equal(h12.Size(), 2)
+	assert.EqualValues(h12.Sum16(), 0xB34)
+	assert.Equal(h12.Sum(nil), []byte{0x0B, 0x34})
+
+	c, _ = CrcByName("CRC-40/GSM")
+	h40, _ := NewCrcHash(c)
+	h40.Write(checkData)
+	assert.Equal(h40.Size(), 5)
+	assert.Equal(h40.Sum(nil), []byte{0xD4, 0x16, 0x4F, 0xC6, 0x46})
+
+	h8, _ := NewCrc8Hash(Crc8Configs[0])
+	h8.Write(checkData)
+	assert.EqualValues(h8.Sum8(), 0xF4)
+	h8.Reset()
+	assert.EqualValues(h8.Sum8(), 0x00)
+}
+
+func TestCrcHashInvalidWidth(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewCrc8Hash(Crc16Configs[0])
+	assert.Equal(err, InvalidWidthError(16))
+	_, err = NewCrc16Hash(Crc32Configs[0])
+	assert.Equal(err, InvalidWidthError(32))
+	_, err = NewCrc32Hash(Crc64Configs[0])
+	assert.Equal(err, InvalidWidthError(64))
+	_, err = NewCrcHash(Config{})
+	assert.Equal(err, InvalidWidthError(0))
+	_, err = NewCrc16Hash(Crc5Configs[0])
+	assert.Nil(err)
+}
+
+func TestCrcContinuePredefined(t *testing.T) {
+	assert := assert.New(t)
+	for i := range Crc8Configs[:10] {
+		expect, _ := Crc8Predefined(testData, i)
+		crc, _ := Crc8Predefined(testData[:77], i)
+		crc, err := Crc8ContinuePredefined(testData[77:], crc, i)
+		assert.Nil(err)
+		assert.Equal(crc, expect)
+	}
+	for i := range Crc16Configs[:20] {
+		expect, _ := Crc16Predefined(testData, i)
+		crc, _ := Crc16Predefined(testData[:77], i)
+		crc, err := Crc16ContinuePredefined(testData[77:], crc, i)
+		assert.Nil(err)
+		assert.Equal(crc, expect)
+	}
+	for i := range Crc32Configs[:9] {
+		expect, _ := Crc32Predefined(testData, i)
+		crc, _ := Crc32Predefined(testData[:77], i)
+		crc, err := Crc32ContinuePredefined(testData[77:], crc, i)
+		assert.Nil(err)
+		assert.Equal(crc, expect)
+	}
+	_, err := Crc16ContinuePredefined(nil, 0, 100)
+	assert.Equal(err, InvalidIndexError(100))
+}
@@ -0,0 +1,507 @@
+package algorithm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+)
+
+// CrcSearchOptions controls SearchCrc
+type CrcSearchOptions struct {
+	// Widths to search, nil means 8, 16, 24 and 32
+	Widths []uint
+	// BruteForce searches parameters which are not in the catalogue
+	BruteForce bool
+	// MaxBruteForceWidth limits the widths of which every odd polynomial is
+	// tried, 0 means 16 and the limit is MaxBruteForceWidthLimit. Wider
+	// polynomials come from the catalogue and from the GCD of samples with
+	// the same length.
+	MaxBruteForceWidth uint
+}
+
+// MaxBruteForceWidthLimit is the largest CrcSearchOptions.MaxBruteForceWidth,
+// 2^23 polynomials are tried for this width
+const MaxBruteForceWidthLimit = 24
+
+// CrcMatch is a config which produces the trailing CRC of all samples, when
+// the CRC is stored in ByteOrder
+type CrcMatch struct {
+	Config    Config
+	ByteOrder binary.ByteOrder
+}
+
+var ErrNoCrcSample = errors.New("core/algorithm/crc: no sample")
+var ErrCrcSampleTooShort = errors.New("core/algorithm/crc: sample too short")
+var ErrBruteForceWidth = errors.New(
+	"core/algorithm/crc: brute force width above MaxBruteForceWidthLimit")
+var ErrCrcUnderdetermined = errors.New(
+	"core/algorithm/crc: too many init values match, add samples of " +
+		"other lengths")
+
+var defaultSearchWidths = []uint{8, 16, 24, 32}
+
+// crcSample is a message split from its trailing CRC
+type crcSample struct {
+	msg []byte
+	crc uint64
+}
+
+func splitCrcSamples(samples [][]byte, width uint,
+	order binary.ByteOrder) ([]crcSample, bool) {
+	n := int(width+7) / 8
+	result := make([]crcSample, len(samples))
+	tmp := make([]byte, 8)
+	for i, s := range samples {
+		for j := range tmp {
+			tmp[j] = 0
+		}
+		if order == binary.LittleEndian {
+			copy(tmp, s[len(s)-n:])
+		} else {
+			copy(tmp[8-n:], s[len(s)-n:])
+		}
+		crc := order.Uint64(tmp)
+		if crc>>(width-1)>>1 != 0 {
+			return nil, false
+		}
+		result[i] = crcSample{s[:len(s)-n], crc}
+	}
+	return result, true
+}
+
+func matchSamples(samples []crcSample, c Config) bool {
+	for _, s := range samples {
+		if crc, _ := CrcWithConfig(s.msg, c); crc != s.crc {
+			return false
+		}
+	}
+	return true
+}
+
+// sameParameters compares the Rocksoft parameters, ignoring the metadata
+func sameParameters(a, b Config) bool {
+	return a.Width == b.Width && a.Polynomial == b.Polynomial &&
+		a.InitValue == b.InitValue && a.ReflectIn == b.ReflectIn &&
+		a.ReflectOut == b.ReflectOut && a.XorResult == b.XorResult
+}
+
+func byteOrders(width uint) []binary.ByteOrder {
+	if width <= 8 {
+		return []binary.ByteOrder{binary.BigEndian}
+	}
+	return []binary.ByteOrder{binary.LittleEndian, binary.BigEndian}
+}
+
+// SearchCrc finds the configs matching all samples, each sample is a message
+// followed by its CRC in (Width+7)/8 bytes. The catalogue is searched first,
+// then if options.BruteForce is set, polynomial, init and xorout are solved
+// for every combination of width, reflection and byte order, like CRC RevEng
+// does. When all samples have the same length, init can not be told from
+// xorout, so init 0 and init with all bits set are both reported.
+// A polynomial for which the lengths of the samples allow more than 256 init
+// values is skipped, ErrCrcUnderdetermined is returned if nothing else
+// matches.
+func SearchCrc(samples [][]byte,
+	options CrcSearchOptions) ([]CrcMatch, error) {
+	return SearchCrcContext(context.Background(), samples, options)
+}
+
+// SearchCrcContext is SearchCrc which stops with the error of ctx when it is
+// done
+func SearchCrcContext(ctx context.Context, samples [][]byte,
+	options CrcSearchOptions) ([]CrcMatch, error) {
+	if len(samples) == 0 {
+		return nil, ErrNoCrcSample
+	}
+	widths := options.Widths
+	if widths == nil {
+		widths = defaultSearchWidths
+	}
+	maxBrute := options.MaxBruteForceWidth
+	if maxBrute == 0 {
+		maxBrute = 16
+	}
+	if maxBrute > MaxBruteForceWidthLimit {
+		return nil, ErrBruteForceWidth
+	}
+	var result []CrcMatch
+	underdetermined := false
+	add := func(c Config, order binary.ByteOrder) {
+		for _, m := range result {
+			if sameParameters(m.Config, c) && m.ByteOrder == order {
+				return
+			}
+		}
+		result = append(result, CrcMatch{c, order})
+	}
+	for _, width := range widths {
+		if width < 1 || width > 64 {
+			return nil, InvalidWidthError(width)
+		}
+		for _, s := range samples {
+			if len(s) < int(width+7)/8 {
+				return nil, ErrCrcSampleTooShort
+			}
+		}
+		for _, order := range byteOrders(width) {
+			split, ok := splitCrcSamples(samples, width, order)
+			if !ok {
+				continue
+			}
+			for _, c := range CrcCatalogue() {
+				if c.Width == width && matchSamples(split, c) {
+					add(c, order)
+				}
+			}
+			if !options.BruteForce {
+				continue
+			}
+			found, err := bruteForceCrc(ctx, split, width, width <= maxBrute)
+			if err == ErrCrcUnderdetermined {
+				underdetermined = true
+			} else if err != nil {
+				return nil, err
+			}
+			for _, c := range found {
+				add(c, order)
+			}
+		}
+	}
+	if len(result) == 0 && underdetermined {
+		return nil, ErrCrcUnderdetermined
+	}
+	return result, nil
+}
+
+// namedConfig fills the metadata of a config found by brute force
+func namedConfig(c Config) Config {
+	for _, known := range CrcCatalogue() {
+		if sameParameters(known, c) {
+			return known
+		}
+	}
+	c.Check = crcByModel([]byte("123456789"), c)
+	c.Residue = crcResidue(c)
+	return c
+}
+
+// zeroInitRegister runs the bitwise algorithm with init 0 and returns the
+// register before reflection and final xor
+func zeroInitRegister(msg []byte, c Config) uint64 {
+	var reg uint64
+	top := uint64(1) << (c.Width - 1)
+	for _, d := range msg {
+		for i := uint(0); i < 8; i++ {
+			var bit uint64
+			if c.ReflectIn {
+				bit = uint64(d>>i) & 1
+			} else {
+				bit = uint64(d>>(7-i)) & 1
+			}
+			if (reg&top > 0) != (bit > 0) {
+				reg = ((reg << 1) ^ c.Polynomial) & c.mask()
+			} else {
+				reg = (reg << 1) & c.mask()
+			}
+		}
+	}
+	return reg
+}
+
+// forEachPolynomial calls f with the polynomials to try for width, all odd
+// ones when exhaustive is set, otherwise the catalogue ones and those found
+// by the GCD of the samples. It stops at the first error of f or ctx.
+func forEachPolynomial(ctx context.Context, samples []crcSample, width uint,
+	exhaustive bool, f func(poly uint64) error) error {
+	if exhaustive {
+		max := ^uint64(0) >> (64 - width)
+		for poly := uint64(1); ; poly += 2 {
+			if poly&0xFFF == 1 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			if err := f(poly); err != nil {
+				return err
+			}
+			if poly >= max-1 {
+				return nil
+			}
+		}
+	}
+	seen := make(map[uint64]bool)
+	for _, c := range CrcCatalogue() {
+		if c.Width == width && !seen[c.Polynomial] {
+			seen[c.Polynomial] = true
+			if err := f(c.Polynomial); err != nil {
+				return err
+			}
+		}
+	}
+	for _, refin := range []bool{false, true} {
+		for _, refout := range []bool{false, true} {
+			poly, ok := gcdPolynomial(samples, width, refin, refout)
+			if ok && !seen[poly] {
+				seen[poly] = true
+				if err := f(poly); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// gcdPolynomial uses the fact that the generator polynomial divides
+// (m1+m2)*x^width + (crc1+crc2) when m1 and m2 have the same length
+func gcdPolynomial(samples []crcSample, width uint,
+	refin, refout bool) (uint64, bool) {
+	groups := make(map[int]crcSample)
+	var gcd *big.Int
+	for _, s := range samples {
+		first, ok := groups[len(s.msg)]
+		if !ok {
+			groups[len(s.msg)] = s
+			continue
+		}
+		d := new(big.Int)
+		for i := range s.msg {
+			b := first.msg[i] ^ s.msg[i]
+			if refin {
+				b = reflectByte(b)
+			}
+			d.Lsh(d, 8)
+			d.Or(d, big.NewInt(int64(b)))
+		}
+		crc := first.crc ^ s.crc
+		if refout {
+			crc = reflectBits(crc, width)
+		}
+		d.Lsh(d, width)
+		d.Xor(d, new(big.Int).SetUint64(crc))
+		if d.Sign() == 0 {
+			continue
+		}
+		if gcd == nil {
+			gcd = d
+		} else {
+			gcd = gf2Gcd(gcd, d)
+		}
+	}
+	if gcd == nil || gcd.BitLen() != int(width)+1 || gcd.Bit(0) == 0 {
+		return 0, false
+	}
+	gcd.SetBit(gcd, int(width), 0)
+	return gcd.Uint64(), true
+}
+
+func reflectByte(b byte) byte {
+	return byte(reflectBits(uint64(b), 8))
+}
+
+func gf2Mod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Set(a)
+	for r.BitLen() >= b.BitLen() {
+		r.Xor(r, new(big.Int).Lsh(b, uint(r.BitLen()-b.BitLen())))
+	}
+	return r
+}
+
+func gf2Gcd(a, b *big.Int) *big.Int {
+	for b.Sign() != 0 {
+		a, b = b, gf2Mod(a, b)
+	}
+	return a
+}
+
+// bruteForceCrc solves init and xorout for every polynomial candidate. With
+// init 0 the CRC is linear, so for each sample
+// k = in(crc) + reg0(msg) = init*x^(8*len) + in(xorout) mod P,
+// in() is the reflection when ReflectOut is set. The candidates are checked
+// with this model instead of the table engine, which would cache a table
+// for every polynomial. A polynomial with too many init values is skipped,
+// ErrCrcUnderdetermined is then returned with the configs of the others.
+func bruteForceCrc(ctx context.Context, samples []crcSample, width uint,
+	exhaustive bool) ([]Config, error) {
+	var result []Config
+	underdetermined := false
+	err := forEachPolynomial(ctx, samples, width, exhaustive,
+		func(poly uint64) error {
+			for _, refin := range []bool{false, true} {
+				c := Config{Width: width, Polynomial: poly, ReflectIn: refin}
+				regs := make([]uint64, len(samples))
+				for i, s := range samples {
+					regs[i] = zeroInitRegister(s.msg, c)
+				}
+				for _, refout := range []bool{false, true} {
+					c.ReflectOut = refout
+					configs, err := solveInitXor(samples, regs, c)
+					if err == ErrCrcUnderdetermined {
+						underdetermined = true
+						continue
+					} else if err != nil {
+						return err
+					}
+					for _, found := range configs {
+						result = append(result, namedConfig(found))
+					}
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Polynomial < result[j].Polynomial
+	})
+	if underdetermined {
+		return result, ErrCrcUnderdetermined
+	}
+	return result, nil
+}
+
+// crcByModel is the bitwise CRC, slow but without table
+func crcByModel(msg []byte, c Config) uint64 {
+	reg := zeroInitRegister(msg, c) ^ mulMod(c.InitValue&c.mask(),
+		xPowMod(uint64(len(msg))*8, c), c)
+	if c.ReflectOut {
+		reg = reflectBits(reg, c.Width)
+	}
+	return (reg ^ c.XorResult) & c.mask()
+}
+
+func solveInitXor(samples []crcSample, regs []uint64,
+	c Config) ([]Config, error) {
+	k := make([]uint64, len(samples))
+	for i, s := range samples {
+		k[i] = s.crc
+		if c.ReflectOut {
+			k[i] = reflectBits(k[i], c.Width)
+		}
+		k[i] ^= regs[i]
+	}
+	// all k of the same length must be the same
+	byLength := make(map[int]uint64)
+	var lengths []int
+	for i, s := range samples {
+		if v, ok := byLength[len(s.msg)]; ok {
+			if v != k[i] {
+				return nil, nil
+			}
+			continue
+		}
+		byLength[len(s.msg)] = k[i]
+		lengths = append(lengths, len(s.msg))
+	}
+	var inits []uint64
+	if len(lengths) == 1 {
+		inits = []uint64{0, c.mask()}
+	} else {
+		var err error
+		if inits, err = solveInit(lengths, byLength, c); err != nil {
+			return nil, err
+		}
+	}
+	var result []Config
+	for _, init := range inits {
+		x := byLength[lengths[0]] ^
+			mulMod(init, xPowMod(uint64(lengths[0])*8, c), c)
+		if c.ReflectOut {
+			x = reflectBits(x, c.Width)
+		}
+		found := c
+		found.InitValue = init
+		found.XorResult = x
+		result = append(result, found)
+	}
+	return result, nil
+}
+
+// solveInit solves init*(x^(8*len0) + x^(8*len)) = k0 + k for every other
+// length by Gaussian elimination over GF(2), it returns ErrCrcUnderdetermined
+// when more than 8 bits of init are free
+func solveInit(lengths []int, k map[int]uint64,
+	c Config) ([]uint64, error) {
+	type equation struct {
+		coef uint64
+		rhs  uint64
+	}
+	var eqs []equation
+	x0 := xPowMod(uint64(lengths[0])*8, c)
+	for _, l := range lengths[1:] {
+		q := x0 ^ xPowMod(uint64(l)*8, c)
+		y := k[lengths[0]] ^ k[l]
+		var columns []uint64
+		for j := uint(0); j < c.Width; j++ {
+			columns = append(columns, mulMod(uint64(1)<<j, q, c))
+		}
+		for bit := uint(0); bit < c.Width; bit++ {
+			var e equation
+			for j, col := range columns {
+				e.coef |= ((col >> bit) & 1) << uint(j)
+			}
+			e.rhs = (y >> bit) & 1
+			eqs = append(eqs, e)
+		}
+	}
+	var pivots []int
+	row := 0
+	for j := uint(0); j < c.Width && row < len(eqs); j++ {
+		p := -1
+		for i := row; i < len(eqs); i++ {
+			if (eqs[i].coef>>j)&1 > 0 {
+				p = i
+				break
+			}
+		}
+		if p < 0 {
+			continue
+		}
+		eqs[row], eqs[p] = eqs[p], eqs[row]
+		for i := range eqs {
+			if i != row && (eqs[i].coef>>j)&1 > 0 {
+				eqs[i].coef ^= eqs[row].coef
+				eqs[i].rhs ^= eqs[row].rhs
+			}
+		}
+		pivots = append(pivots, int(j))
+		row++
+	}
+	for _, e := range eqs[row:] {
+		if e.rhs != 0 {
+			return nil, nil
+		}
+	}
+	var free []uint
+	isPivot := make(map[int]bool)
+	for _, p := range pivots {
+		isPivot[p] = true
+	}
+	for j := uint(0); j < c.Width; j++ {
+		if !isPivot[int(j)] {
+			free = append(free, j)
+		}
+	}
+	if len(free) > 8 {
+		return nil, ErrCrcUnderdetermined
+	}
+	var result []uint64
+	for m := 0; m < 1<<uint(len(free)); m++ {
+		var init uint64
+		for i, j := range free {
+			init |= uint64((m>>uint(i))&1) << j
+		}
+		for r, p := range pivots {
+			v := eqs[r].rhs
+			for _, j := range free {
+				v ^= ((eqs[r].coef >> j) & 1) & ((init >> j) & 1)
+			}
+			init |= v << uint(p)
+		}
+		result = append(result, init)
+	}
+	return result, nil
+}
@@ -0,0 +1,198 @@
+package algorithm
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+// makeCrcSamples makes messages of the given lengths followed by their CRC
+func makeCrcSamples(c Config, order binary.ByteOrder,
+	lengths ...int) [][]byte {
+	r := rand.New(rand.NewSource(int64(c.Polynomial)))
+	var result [][]byte
+	n := int(c.Width+7) / 8
+	for _, l := range lengths {
+		msg := make([]byte, l)
+		r.Read(msg)
+		crc, _ := CrcWithConfig(msg, c)
+		tmp := make([]byte, 8)
+		if order == binary.LittleEndian {
+			binary.LittleEndian.PutUint64(tmp, crc)
+			msg = append(msg, tmp[:n]...)
+		} else {
+			binary.BigEndian.PutUint64(tmp, crc)
+			msg = append(msg, tmp[8-n:]...)
+		}
+		result = append(result, msg)
+	}
+	return result
+}
+
+func containsMatch(matches []CrcMatch, c Config,
+	order binary.ByteOrder) bool {
+	for _, m := range matches {
+		if sameParameters(m.Config, c) && m.ByteOrder == order {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSearchCrcCatalogue(t *testing.T) {
+	assert := assert.New(t)
+	c, _ := CrcByName("CRC-16/MODBUS")
+	samples := makeCrcSamples(c, binary.LittleEndian, 6, 6, 8, 20)
+	matches, err := SearchCrc(samples, CrcSearchOptions{})
+	if assert.Nil(err) && assert.Len(matches, 1) {
+		assert.Equal(matches[0].Config, c)
+		assert.Equal(matches[0].ByteOrder, binary.LittleEndian)
+	}
+	c, _ = CrcByName("CRC-12/UMTS")
+	samples = makeCrcSamples(c, binary.BigEndian, 5, 9, 9)
+	matches, err = SearchCrc(samples, CrcSearchOptions{Widths: []uint{12}})
+	if assert.Nil(err) && assert.Len(matches, 1) {
+		assert.Equal(matches[0].Config, c)
+		assert.Equal(matches[0].ByteOrder, binary.BigEndian)
+	}
+}
+
+func TestSearchCrcBruteForce(t *testing.T) {
+	assert := assert.New(t)
+	c := Config{Width: 16, Polynomial: 0x1DCF, InitValue: 0x1234,
+		ReflectIn: true, XorResult: 0x5555}
+	samples := makeCrcSamples(c, binary.BigEndian, 10, 10, 10, 7, 7, 13)
+	matches, err := SearchCrc(samples, CrcSearchOptions{
+		Widths: []uint{16}, BruteForce: true})
+	if assert.Nil(err) && assert.Len(matches, 1) {
+		found := matches[0].Config
+		assert.True(sameParameters(found, c), "%+v", found)
+		assert.Equal(found.Check, crcByModel(checkData, c))
+		check, _ := CrcWithConfig(checkData, c)
+		assert.Equal(found.Check, check)
+		assert.Equal(matches[0].ByteOrder, binary.BigEndian)
+	}
+
+	c = Config{Width: 7, Polynomial: 0x09, InitValue: 0x7F, XorResult: 0x01}
+	samples = makeCrcSamples(c, binary.BigEndian, 4, 4, 4, 9, 9)
+	matches, err = SearchCrc(samples, CrcSearchOptions{
+		Widths: []uint{7}, BruteForce: true})
+	if assert.Nil(err) {
+		assert.True(containsMatch(matches, c, binary.BigEndian))
+	}
+}
+
+func TestSearchCrcGcd(t *testing.T) {
+	assert := assert.New(t)
+	// CRC-32K by Koopman is not in the catalogue
+	c := Config{Width: 32, Polynomial: 0x741B8CD7, InitValue: 0xFFFFFFFF,
+		ReflectIn: true, ReflectOut: true, XorResult: 0xFFFFFFFF}
+	samples := makeCrcSamples(
+		c, binary.LittleEndian, 16, 16, 16, 16, 24, 24, 30)
+	matches, err := SearchCrc(samples, CrcSearchOptions{
+		Widths: []uint{32}, BruteForce: true})
+	// the polynomial has the factor x+1, so another init and xorout give
+	// the same CRC for every length
+	if assert.Nil(err) && assert.NotEmpty(matches) {
+		assert.True(containsMatch(matches, c, binary.LittleEndian))
+		for _, m := range matches {
+			assert.EqualValues(m.Config.Check, 0x2D3DD0AE)
+			assert.Equal(m.Config.Residue, crcResidue(m.Config))
+		}
+	}
+}
+
+func TestSearchCrcSameLength(t *testing.T) {
+	assert := assert.New(t)
+	c := Config{Width: 8, Polynomial: 0x2F, InitValue: 0xFF, XorResult: 0xFF}
+	samples := makeCrcSamples(c, binary.BigEndian, 5, 5, 5, 5)
+	matches, err := SearchCrc(samples, CrcSearchOptions{
+		Widths: []uint{8}, BruteForce: true})
+	if assert.Nil(err) {
+		assert.True(containsMatch(matches, c, binary.BigEndian))
+		for _, m := range matches {
+			assert.Contains([]uint64{0, 0xFF}, m.Config.InitValue)
+		}
+	}
+}
+
+func TestSearchCrcError(t *testing.T) {
+	assert := assert.New(t)
+	_, err := SearchCrc(nil, CrcSearchOptions{})
+	assert.Equal(err, ErrNoCrcSample)
+	_, err = SearchCrc([][]byte{{0x01}}, CrcSearchOptions{})
+	assert.Equal(err, ErrCrcSampleTooShort)
+	_, err = SearchCrc([][]byte{{0x01}}, CrcSearchOptions{Widths: []uint{0}})
+	assert.Equal(err, InvalidWidthError(0))
+	matches, err := SearchCrc([][]byte{{0x01, 0x02, 0x03}},
+		CrcSearchOptions{Widths: []uint{16}})
+	assert.Nil(err)
+	assert.Empty(matches)
+}
+
+func TestCrcResidue(t *testing.T) {
+	assert := assert.New(t)
+	for _, configs := range allConfigs {
+		for _, c := range configs {
+			assert.Equal(c.Residue, crcResidue(c), c.Name)
+			assert.Equal(c.Check, crcByModel(checkData, c), c.Name)
+		}
+	}
+}
+
+func TestSearchCrcLimits(t *testing.T) {
+	assert := assert.New(t)
+	samples := [][]byte{{0x01, 0x02, 0x03}}
+	_, err := SearchCrc(samples, CrcSearchOptions{Widths: []uint{16},
+		BruteForce: true, MaxBruteForceWidth: MaxBruteForceWidthLimit + 1})
+	assert.Equal(err, ErrBruteForceWidth)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = SearchCrcContext(ctx, samples, CrcSearchOptions{
+		Widths: []uint{16}, BruteForce: true})
+	assert.Equal(err, context.Canceled)
+	// the catalogue is searched without checking ctx
+	_, err = SearchCrcContext(ctx, samples, CrcSearchOptions{
+		Widths: []uint{16}})
+	assert.Nil(err)
+}
+
+func TestSearchCrcSkipUnderdetermined(t *testing.T) {
+	assert := assert.New(t)
+	c, err := CrcByName("CRC-12/DECT")
+	assert.Nil(err)
+	// x^16 = x^40 mod x^12+1, so for this polynomial init can not be solved
+	// from messages of 2 and 5 bytes, the messages are chosen to give the
+	// same xorout for it
+	var samples [][]byte
+	for _, msg := range [][]byte{{0x12, 0x34, 0x56, 0x78, 0x9A},
+		{0x12, 0x34, 0x56, 0x00, 0xAE}, {0x04, 0x8B}, {0x0C, 0x8E}} {
+		sample, _ := AppendCrcWithConfig(msg, c, binary.BigEndian)
+		samples = append(samples, sample)
+	}
+	matches, err := SearchCrc(samples, CrcSearchOptions{Widths: []uint{12},
+		BruteForce: true})
+	assert.Nil(err)
+	found := false
+	for _, m := range matches {
+		if m.Config.Name == c.Name && m.ByteOrder == binary.BigEndian {
+			found = true
+		}
+	}
+	assert.True(found)
+}
+
+func TestSolveInitUnderdetermined(t *testing.T) {
+	assert := assert.New(t)
+	// x^16 = 1 mod x^16+1, so messages of 2 and 4 bytes tell nothing of init
+	c := Config{Width: 16, Polynomial: 0x0001}
+	_, err := solveInit([]int{2, 4}, map[int]uint64{2: 0, 4: 0}, c)
+	assert.Equal(err, ErrCrcUnderdetermined)
+	// x^8+1 leaves 8 free bits, the 256 values are returned
+	inits, err := solveInit([]int{1, 2}, map[int]uint64{1: 0, 2: 0}, c)
+	assert.Nil(err)
+	assert.Len(inits, 256)
+}
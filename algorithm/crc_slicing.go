@@ -0,0 +1,98 @@
+package algorithm
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// slicing8Cutoff is the minimum length processed by slicing-by-8, shorter
+// data is not worth the extra table lookups
+const slicing8Cutoff = 16
+
+// crcSlicingTable holds the tables of the slicing-by-8 algorithm, table[0] is
+// the table of MakeCrcTable and table[k] gives the effect of a byte followed
+// by k zero bytes. When the register update is the same as hash/crc32, std is
+// set and the standard library (hardware accelerated on most platforms) is
+// used instead.
+// Reference:
+// https://create.stephan-brumme.com/crc32/#slicing-by-8-overview
+type crcSlicingTable struct {
+	refin bool
+	table [8][256]uint64
+	std   *crc32.Table
+}
+
+func makeCrcSlicingTable(config Config) *crcSlicingTable {
+	t := &crcSlicingTable{refin: config.ReflectIn}
+	copy(t.table[0][:], MakeCrcTable(config))
+	for i := 0; i < 256; i++ {
+		crc := t.table[0][i]
+		for k := 1; k < 8; k++ {
+			if t.refin {
+				crc = (crc >> 8) ^ t.table[0][byte(crc)]
+			} else {
+				crc = (crc << 8) ^ t.table[0][byte(crc>>56)]
+			}
+			t.table[k][i] = crc
+		}
+	}
+	if config.Width == 32 && config.ReflectIn {
+		switch uint32(config.Polynomial) {
+		case 0x04C11DB7:
+			t.std = crc32.IEEETable
+		case 0x1EDC6F41:
+			t.std = crc32.MakeTable(crc32.Castagnoli)
+		}
+	}
+	return t
+}
+
+// update works on the register of the engine, init and final xor are handled
+// by loadRegister and finalRegister, so hash/crc32 gets the complemented
+// register it expects whatever InitValue and XorResult are.
+func (t *crcSlicingTable) update(reg uint64, data []byte) uint64 {
+	if t.std != nil {
+		return uint64(^crc32.Update(^uint32(reg), t.std, data))
+	}
+	if len(data) >= slicing8Cutoff {
+		if t.refin {
+			reg, data = t.updateReflected8(reg, data)
+		} else {
+			reg, data = t.update8(reg, data)
+		}
+	}
+	return updateRegister(reg, data, t.table[0][:], t.refin)
+}
+
+func (t *crcSlicingTable) updateReflected8(reg uint64,
+	data []byte) (uint64, []byte) {
+	for len(data) >= 8 {
+		reg ^= binary.LittleEndian.Uint64(data)
+		reg = t.table[7][byte(reg)] ^
+			t.table[6][byte(reg>>8)] ^
+			t.table[5][byte(reg>>16)] ^
+			t.table[4][byte(reg>>24)] ^
+			t.table[3][byte(reg>>32)] ^
+			t.table[2][byte(reg>>40)] ^
+			t.table[1][byte(reg>>48)] ^
+			t.table[0][byte(reg>>56)]
+		data = data[8:]
+	}
+	return reg, data
+}
+
+func (t *crcSlicingTable) update8(reg uint64, data []byte) (uint64, []byte) {
+	for len(data) >= 8 {
+		reg ^= binary.BigEndian.Uint64(data)
+		reg = t.table[7][byte(reg>>56)] ^
+			t.table[6][byte(reg>>48)] ^
+			t.table[5][byte(reg>>40)] ^
+			t.table[4][byte(reg>>32)] ^
+			t.table[3][byte(reg>>24)] ^
+			t.table[2][byte(reg>>16)] ^
+			t.table[1][byte(reg>>8)] ^
+			t.table[0][byte(reg)]
+		data = data[8:]
+	}
+	return reg, data
+}
@@ -0,0 +1,138 @@
+package algorithm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"math/rand"
+	"testing"
+)
+
+var benchData = makeRandomData(1 << 20)
+
+func makeRandomData(n int) []byte {
+	ret := make([]byte, n, n)
+	rand.New(rand.NewSource(1)).Read(ret)
+	return ret
+}
+
+func TestCrcSlicingAllConfigs(t *testing.T) {
+	assert := assert.New(t)
+	data := makeRandomData(1000)
+	for _, configs := range allConfigs {
+		for _, c := range configs {
+			table := MakeCrcTable(c)
+			for _, n := range []int{0, 7, 8, 15, 16, 17, 64, 996} {
+				for _, offset := range []int{0, 1, 3} {
+					p := data[offset : offset+n]
+					expect := CrcImpl(p, table, c)
+					crc, _ := CrcWithConfig(p, c)
+					assert.Equal(expect, crc, "%s %d %d", c.Name, n, offset)
+				}
+			}
+		}
+	}
+}
+
+func TestCrcSlicingWithoutStd(t *testing.T) {
+	assert := assert.New(t)
+	data := makeRandomData(1000)
+	for _, name := range []string{"CRC-32", "CRC-32C", "CRC-32/JAMCRC"} {
+		c, _ := CrcByName(name)
+		st := makeCrcSlicingTable(c)
+		assert.NotNil(st.std, name)
+		reg := loadRegister(c.InitValue, c)
+		std := finalRegister(st.update(reg, data), c)
+		st.std = nil
+		assert.Equal(finalRegister(st.update(reg, data), c), std, name)
+		assert.Equal(CrcImpl(data, MakeCrcTable(c), c), std, name)
+	}
+	c, _ := CrcByName("CRC-32/BZIP2")
+	assert.Nil(makeCrcSlicingTable(c).std)
+	c, _ = CrcByName("CRC-32D")
+	assert.Nil(makeCrcSlicingTable(c).std)
+}
+
+func TestCrcSlicingStd(t *testing.T) {
+	assert := assert.New(t)
+	crc, _ := CrcWithConfig(benchData, Crc32Configs[0])
+	assert.EqualValues(crc, crc32.ChecksumIEEE(benchData))
+	crc, _ = CrcWithConfig(benchData, Crc32Configs[2])
+	assert.EqualValues(crc, crc32.Checksum(benchData,
+		crc32.MakeTable(crc32.Castagnoli)))
+}
+
+func benchmarkCrc(b *testing.B, name string) {
+	c, _ := CrcByName(name)
+	b.SetBytes(int64(len(benchData)))
+	for i := 0; i < b.N; i++ {
+		CrcWithConfig(benchData, c)
+	}
+}
+
+func benchmarkCrcImpl(b *testing.B, name string) {
+	c, _ := CrcByName(name)
+	table := MakeCrcTable(c)
+	b.SetBytes(int64(len(benchData)))
+	for i := 0; i < b.N; i++ {
+		CrcImpl(benchData, table, c)
+	}
+}
+
+func BenchmarkCrc32(b *testing.B) {
+	benchmarkCrc(b, "CRC-32")
+}
+
+func BenchmarkCrc32C(b *testing.B) {
+	benchmarkCrc(b, "CRC-32C")
+}
+
+func BenchmarkCrc32BZIP2(b *testing.B) {
+	benchmarkCrc(b, "CRC-32/BZIP2")
+}
+
+func BenchmarkCrc32D(b *testing.B) {
+	benchmarkCrc(b, "CRC-32D")
+}
+
+func BenchmarkCrc16Modbus(b *testing.B) {
+	benchmarkCrc(b, "CRC-16/MODBUS")
+}
+
+func BenchmarkCrc16XModem(b *testing.B) {
+	benchmarkCrc(b, "CRC-16/XMODEM")
+}
+
+func BenchmarkCrc64XZ(b *testing.B) {
+	benchmarkCrc(b, "CRC-64/XZ")
+}
+
+func BenchmarkCrcImpl32D(b *testing.B) {
+	benchmarkCrcImpl(b, "CRC-32D")
+}
+
+func BenchmarkCrcImpl16XModem(b *testing.B) {
+	benchmarkCrcImpl(b, "CRC-16/XMODEM")
+}
+
+func BenchmarkCrc32PredefinedLegacy(b *testing.B) {
+	table := MakeCrc32Table(0xA833982B)
+	b.SetBytes(int64(len(benchData)))
+	for i := 0; i < b.N; i++ {
+		Crc32Impl(benchData, table, 0xFFFFFFFF, true, true, 0xFFFFFFFF)
+	}
+}
+
+func BenchmarkStdCrc32IEEE(b *testing.B) {
+	b.SetBytes(int64(len(benchData)))
+	for i := 0; i < b.N; i++ {
+		crc32.ChecksumIEEE(benchData)
+	}
+}
+
+func BenchmarkStdCrc32Castagnoli(b *testing.B) {
+	table := crc32.MakeTable(crc32.Castagnoli)
+	b.SetBytes(int64(len(benchData)))
+	for i := 0; i < b.N; i++ {
+		crc32.Checksum(benchData, table)
+	}
+}
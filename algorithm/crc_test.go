@@ -1,7 +1,9 @@
 package algorithm
 
 import (
+	"encoding/binary"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
 )
 
@@ -264,7 +266,8 @@ func TestChangeCrc8ConfigIndex(t *testing.T) {
 	_, err = Crc32Continue([]byte{0x10}, 0)
 	assert.Error(err)
 
-	Crc8Configs = append(Crc8Configs, Config{0x9B, 0x00, 0x00, true, true})
+	Crc8Configs = append(Crc8Configs,
+		Config{Polynomial: 0x9B, ReflectIn: true, ReflectOut: true})
 	DefaultCrc8ConfigIndex = len(Crc8Configs) - 1
 	if v, err := Crc8Continue([]byte{0x10},
 		byte(Crc8Configs[DefaultCrc8ConfigIndex].InitValue)); assert.Nil(err) {
@@ -272,7 +275,7 @@ func TestChangeCrc8ConfigIndex(t *testing.T) {
 	}
 
 	Crc16Configs = append(
-		Crc16Configs, Config{0x1021, 0x0000, 0x0000, false, false})
+		Crc16Configs, Config{Polynomial: 0x1021})
 	DefaultCrc16ConfigIndex = len(Crc16Configs) - 1
 	if v, err := Crc16Continue([]byte{0x10}, uint16(
 		Crc16Configs[DefaultCrc16ConfigIndex].InitValue)); assert.Nil(err) {
@@ -280,10 +283,172 @@ func TestChangeCrc8ConfigIndex(t *testing.T) {
 	}
 
 	Crc32Configs = append(
-		Crc32Configs, Config{0xAF, 0, 0, false, false})
+		Crc32Configs, Config{Polynomial: 0xAF})
 	DefaultCrc32ConfigIndex = len(Crc32Configs) - 1
 	if v, err := Crc32Continue([]byte{0x10}, uint32(
 		Crc32Configs[DefaultCrc32ConfigIndex].InitValue)); assert.Nil(err) {
 		assert.EqualValues(v, 0xAF0)
 	}
 }
+
+var checkData = []byte("123456789")
+
+var allConfigs = [][]Config{
+	Crc5Configs, Crc7Configs, Crc8Configs, Crc12Configs, Crc15Configs,
+	Crc16Configs, Crc24Configs, Crc32Configs, Crc40Configs, Crc64Configs,
+}
+
+func TestCrcWithConfigCheck(t *testing.T) {
+	assert := assert.New(t)
+	for _, configs := range allConfigs {
+		for _, c := range configs {
+			crc, err := CrcWithConfig(checkData, c)
+			if assert.Nil(err) {
+				assert.Equal(c.Check, crc, "%+v", c)
+			}
+		}
+	}
+}
+
+// appendCodeword appends crc in the order it is shifted out by the bitwise
+// algorithm, only for widths which are multiple of 8
+func appendCodeword(data []byte, crc uint64, c Config) []byte {
+	tmp := make([]byte, 8)
+	n := c.Width / 8
+	if c.ReflectOut {
+		binary.LittleEndian.PutUint64(tmp, crc)
+		return append(data, tmp[:n]...)
+	}
+	binary.BigEndian.PutUint64(tmp, crc)
+	return append(data, tmp[8-n:]...)
+}
+
+func TestCrcWithConfigResidue(t *testing.T) {
+	assert := assert.New(t)
+	for _, configs := range allConfigs {
+		for _, c := range configs {
+			if c.Width%8 != 0 {
+				continue
+			}
+			data := appendCodeword(
+				append([]byte(nil), checkData...), c.Check, c)
+			crc, _ := CrcWithConfig(data, c)
+			assert.Equal(c.Residue^c.XorResult, crc, "%+v", c)
+		}
+	}
+}
+
+func TestCrcContinueWithConfig(t *testing.T) {
+	assert := assert.New(t)
+	for _, configs := range allConfigs {
+		for _, c := range configs {
+			expect, _ := CrcWithConfig(testData, c)
+			for _, n := range []int{0, 1, 100, 255, 256} {
+				crc, _ := CrcWithConfig(testData[:n], c)
+				crc, err := CrcContinueWithConfig(testData[n:], crc, c)
+				if assert.Nil(err) {
+					assert.Equal(expect, crc, "%+v", c)
+				}
+			}
+		}
+	}
+}
+
+func TestCrcWithConfigWrapper(t *testing.T) {
+	assert := assert.New(t)
+	for i := range Crc8Configs {
+		c, _ := predefinedConfig(Crc8Configs, i, 8)
+		expect, _ := Crc8Predefined(testData, i)
+		crc, _ := CrcWithConfig(testData, c)
+		assert.EqualValues(expect, crc)
+	}
+	for i := range Crc16Configs {
+		c, _ := predefinedConfig(Crc16Configs, i, 16)
+		expect, _ := Crc16Predefined(testData, i)
+		crc, _ := CrcWithConfig(testData, c)
+		assert.EqualValues(expect, crc)
+	}
+	for i := range Crc32Configs {
+		c, _ := predefinedConfig(Crc32Configs, i, 32)
+		expect, _ := Crc32Predefined(testData, i)
+		crc, _ := CrcWithConfig(testData, c)
+		assert.EqualValues(expect, crc)
+	}
+	_, err := predefinedConfig(Crc8Configs, -1, 8)
+	assert.Equal(err, InvalidIndexError(-1))
+}
+
+func TestCrcImpl(t *testing.T) {
+	assert := assert.New(t)
+	c := Config{Width: 3, Polynomial: 0x3, XorResult: 0x7}
+	table := MakeCrcTable(c)
+	assert.Equal(len(table), 256)
+	assert.EqualValues(CrcImpl(checkData, table, c), 0x4) // CRC-3/GSM
+	c = Config{Width: 6, Polynomial: 0x19, ReflectIn: true, ReflectOut: true}
+	assert.EqualValues(CrcImpl(checkData, MakeCrcTable(c), c), 0x26) // CRC-6/G-704
+	assert.EqualValues(CrcContinueImpl(checkData[4:], MakeCrcTable(c), c,
+		CrcImpl(checkData[:4], MakeCrcTable(c), c)), 0x26)
+}
+
+func TestCrcWithConfigInvalidWidth(t *testing.T) {
+	assert := assert.New(t)
+	if _, err := CrcWithConfig(nil, Config{}); assert.NotNil(err) {
+		assert.EqualError(err, "core/algorithm/crc: invalid width: 0")
+	}
+	_, err := CrcContinueWithConfig(nil, 0, Config{Width: 65})
+	assert.Equal(err, InvalidWidthError(65))
+	assert.Nil(Config{Width: 1}.Validate())
+	assert.Nil(Config{Width: 64}.Validate())
+}
+
+// TestCrcConcurrent is meaningful with -race, all goroutines start together
+// with an empty table cache
+func TestCrcConcurrent(t *testing.T) {
+	assert := assert.New(t)
+	crcTableCache.Range(func(key, _ interface{}) bool {
+		crcTableCache.Delete(key)
+		return true
+	})
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for round := 0; round < 4; round++ {
+		for _, configs := range allConfigs {
+			for i, c := range configs {
+				wg.Add(1)
+				go func(i int, c Config) {
+					defer wg.Done()
+					<-start
+					crc, err := CrcWithConfig(checkData, c)
+					assert.Nil(err)
+					assert.Equal(crc, c.Check)
+					h, _ := NewCrcHash(c)
+					h.Write(checkData)
+					assert.Equal(h.Sum64(), c.Check)
+					switch c.Width {
+					case 8:
+						crc8, _ := Crc8Predefined(checkData, i)
+						assert.EqualValues(crc8, c.Check)
+					case 16:
+						crc16, _ := Crc16Predefined(checkData, i)
+						assert.EqualValues(crc16, c.Check)
+					case 32:
+						crc32, _ := Crc32Predefined(checkData, i)
+						assert.EqualValues(crc32, c.Check)
+					}
+				}(i, c)
+			}
+		}
+	}
+	close(start)
+	wg.Wait()
+}
+
+func TestCrcTableShared(t *testing.T) {
+	assert := assert.New(t)
+	a := crcTable(Crc16Configs[17]) // CRC-16/MODBUS
+	b := crcTable(Crc16Configs[15]) // CRC-16/USB
+	assert.True(a == b)
+	assert.Equal(a.table[0][:], MakeCrcTable(Crc16Configs[17]))
+	c := crcTable(Crc16Configs[19]) // CRC-16/XMODEM
+	assert.True(a != c)
+}
@@ -0,0 +1,103 @@
+package algorithm
+
+import (
+	"encoding/binary"
+)
+
+// Crc calculates the CRC of a fixed config, it does not depend on the
+// Default*ConfigIndex variables, so the wire format can not be changed by
+// other code. A Crc is immutable and safe for concurrent use.
+type Crc struct {
+	config Config
+	order  binary.ByteOrder
+	table  *crcSlicingTable
+	// residue is the CRC of a codeword in the natural order
+	residue uint64
+}
+
+// NewCrc creates a Crc for config, the CRC is appended in little-endian as
+// AppendCrc16 does, use WithByteOrder to change it
+func NewCrc(config Config) (*Crc, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Crc{
+		config:  config,
+		order:   binary.LittleEndian,
+		table:   crcTable(config),
+		residue: (crcResidue(config) ^ config.XorResult) & config.mask(),
+	}, nil
+}
+
+// MustNewCrc is like NewCrc but panics on error, it simplifies the
+// initialization of package variables
+func MustNewCrc(config Config) *Crc {
+	c, err := NewCrc(config)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewCrcByName creates a Crc for a config of the catalogue, see CrcByName
+func NewCrcByName(name string) (*Crc, error) {
+	config, err := CrcByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewCrc(config)
+}
+
+// WithByteOrder returns a copy of c which appends and verifies the CRC in
+// the given byte order
+func (c *Crc) WithByteOrder(order binary.ByteOrder) *Crc {
+	result := *c
+	result.order = order
+	return &result
+}
+
+func (c *Crc) Config() Config {
+	return c.config
+}
+
+func (c *Crc) ByteOrder() binary.ByteOrder {
+	return c.order
+}
+
+// Size is the number of bytes appended by Append
+func (c *Crc) Size() int {
+	return crcSize(c.config)
+}
+
+func (c *Crc) Checksum(data []byte) uint64 {
+	reg := c.table.update(loadRegister(c.config.InitValue, c.config), data)
+	return finalRegister(reg, c.config)
+}
+
+// Continue continues the calculation of a CRC, prev is the CRC of the data
+// before
+func (c *Crc) Continue(data []byte, prev uint64) uint64 {
+	reg := c.table.update(resumeRegister(prev, c.config), data)
+	return finalRegister(reg, c.config)
+}
+
+func (c *Crc) Append(data []byte) []byte {
+	n := c.Size()
+	crc := c.Checksum(data)
+	data = append(data, make([]byte, n)...)
+	putCrc(data[len(data)-n:], crc, n, c.order)
+	return data
+}
+
+// Verify reports whether data ends with the CRC of the bytes before it, like
+// VerifyCrcWithConfig
+func (c *Crc) Verify(data []byte) bool {
+	return verifyCrc(data, c.config, c.order, c.residue, c.Checksum)
+}
+
+// NewHash creates a hash.Hash64 of the config, see NewCrcHash
+func (c *Crc) NewHash() CrcHash {
+	d := &crcDigest{config: c.config, table: c.table}
+	d.Reset()
+	return d
+}
@@ -0,0 +1,77 @@
+package algorithm
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewCrc(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewCrc(Config{})
+	assert.Equal(err, InvalidWidthError(0))
+	assert.Panics(func() { MustNewCrc(Config{Width: 65}) })
+	_, err = NewCrcByName("CRC-1/NONE")
+	assert.Equal(err, UnknownNameError("CRC-1/NONE"))
+	c, err := NewCrcByName("modbus")
+	assert.Nil(err)
+	assert.Equal(c.Config().Name, "CRC-16/MODBUS")
+	assert.Equal(c.ByteOrder(), binary.LittleEndian)
+	assert.Equal(c.Size(), 2)
+	be := c.WithByteOrder(binary.BigEndian)
+	assert.Equal(be.ByteOrder(), binary.BigEndian)
+	assert.Equal(c.ByteOrder(), binary.LittleEndian)
+}
+
+func TestCrcValue(t *testing.T) {
+	assert := assert.New(t)
+	orders := []binary.ByteOrder{binary.BigEndian, binary.LittleEndian}
+	for _, configs := range allConfigs {
+		for _, config := range configs {
+			c := MustNewCrc(config)
+			assert.Equal(c.Checksum(checkData), config.Check, config.Name)
+			assert.Equal(c.Continue(checkData[5:], c.Checksum(checkData[:5])),
+				config.Check, config.Name)
+			h := c.NewHash()
+			h.Write(checkData)
+			assert.Equal(h.Sum64(), config.Check, config.Name)
+			for _, order := range orders {
+				c = c.WithByteOrder(order)
+				data := c.Append(append([]byte(nil), checkData...))
+				expected, _ := AppendCrcWithConfig(
+					append([]byte(nil), checkData...), config, order)
+				assert.Equal(data, expected, config.Name)
+				assert.True(c.Verify(data), config.Name)
+				data[len(data)-1] ^= 0x01
+				assert.False(c.Verify(data), config.Name)
+			}
+		}
+	}
+	assert.False(MustNewCrc(Crc32Configs[0]).Verify([]byte{1, 2, 3}))
+}
+
+func TestCrcIgnoreDefault(t *testing.T) {
+	assert := assert.New(t)
+	c := MustNewCrc(Crc16Configs[17])
+	data := c.Append([]byte{0x10})
+	old := DefaultCrc16ConfigIndex
+	DefaultCrc16ConfigIndex = 0
+	defer func() { DefaultCrc16ConfigIndex = old }()
+	assert.Equal(c.Append([]byte{0x10}), data)
+	assert.True(c.Verify(data))
+	assert.False(VerifyCrc16(data))
+}
+
+func TestCrcMixedReflection(t *testing.T) {
+	assert := assert.New(t)
+	orders := []binary.ByteOrder{binary.BigEndian, binary.LittleEndian}
+	for _, config := range mixedReflectionConfigs {
+		for _, order := range orders {
+			c := MustNewCrc(config).WithByteOrder(order)
+			data := c.Append(append([]byte(nil), checkData...))
+			assert.True(c.Verify(data), "%+v %v", config, order)
+			data[0] ^= 0x01
+			assert.False(c.Verify(data), "%+v %v", config, order)
+		}
+	}
+}
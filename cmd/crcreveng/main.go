@@ -0,0 +1,129 @@
+// crcreveng finds the CRC algorithm of messages followed by their CRC.
+//
+// Usage:
+//
+//	crcreveng [-w 8,16,24,32] [-b] [-max-brute 16] [-timeout 1m]
+//	          [hex sample ...]
+//
+// Samples are read from the arguments, or one per line from stdin, spaces
+// and colons between the hex bytes are ignored.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/newkedison/go-utils/algorithm"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func parseWidths(s string) ([]uint, error) {
+	var result []uint
+	for _, w := range strings.Split(s, ",") {
+		v, err := strconv.ParseUint(strings.TrimSpace(w), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid width %q", w)
+		}
+		result = append(result, uint(v))
+	}
+	return result, nil
+}
+
+func parseSample(s string) ([]byte, error) {
+	s = strings.NewReplacer(" ", "", ":", "", "\t", "").Replace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	return hex.DecodeString(s)
+}
+
+func readSamples(args []string, r io.Reader) ([][]byte, error) {
+	if len(args) == 0 {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				args = append(args, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	var result [][]byte
+	for _, arg := range args {
+		sample, err := parseSample(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample %q: %v", arg, err)
+		}
+		result = append(result, sample)
+	}
+	return result, nil
+}
+
+func formatMatch(m algorithm.CrcMatch) string {
+	c := m.Config
+	digits := int(c.Width+3) / 4
+	hexValue := func(v uint64) string {
+		return fmt.Sprintf("0x%0*x", digits, v)
+	}
+	order := "big-endian"
+	if m.ByteOrder == binary.LittleEndian {
+		order = "little-endian"
+	}
+	name := c.Name
+	if name == "" {
+		name = "(none)"
+	}
+	return fmt.Sprintf("width=%d poly=%s init=%s refin=%t refout=%t "+
+		"xorout=%s check=%s residue=%s order=%s name=%q",
+		c.Width, hexValue(c.Polynomial), hexValue(c.InitValue), c.ReflectIn,
+		c.ReflectOut, hexValue(c.XorResult), hexValue(c.Check),
+		hexValue(c.Residue), order, name)
+}
+
+func main() {
+	widths := flag.String("w", "8,16,24,32", "comma separated widths to search")
+	brute := flag.Bool("b", false, "brute force parameters not in the catalogue")
+	maxBrute := flag.Uint("max-brute", 16, fmt.Sprintf(
+		"try every polynomial up to this width when brute forcing, at most %d",
+		algorithm.MaxBruteForceWidthLimit))
+	timeout := flag.Duration("timeout", 0, "stop the search after this time")
+	flag.Parse()
+
+	options := algorithm.CrcSearchOptions{
+		BruteForce:         *brute,
+		MaxBruteForceWidth: *maxBrute,
+	}
+	var err error
+	if options.Widths, err = parseWidths(*widths); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	samples, err := readSamples(flag.Args(), os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	matches, err := algorithm.SearchCrcContext(ctx, samples, options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if len(matches) == 0 {
+		fmt.Fprintln(os.Stderr, "no CRC found")
+		os.Exit(1)
+	}
+	for _, m := range matches {
+		fmt.Println(formatMatch(m))
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/binary"
+	"github.com/newkedison/go-utils/algorithm"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseWidths(t *testing.T) {
+	assert := assert.New(t)
+	widths, err := parseWidths("8, 16,32")
+	assert.Nil(err)
+	assert.Equal(widths, []uint{8, 16, 32})
+	_, err = parseWidths("8,x")
+	assert.NotNil(err)
+}
+
+func TestReadSamples(t *testing.T) {
+	assert := assert.New(t)
+	samples, err := readSamples([]string{"01 03 00:00", "0xFF"}, nil)
+	assert.Nil(err)
+	assert.Equal(samples, [][]byte{{0x01, 0x03, 0x00, 0x00}, {0xFF}})
+	samples, err = readSamples(nil, strings.NewReader("0102\n\n  0304\n"))
+	assert.Nil(err)
+	assert.Equal(samples, [][]byte{{0x01, 0x02}, {0x03, 0x04}})
+	_, err = readSamples([]string{"0G"}, nil)
+	assert.NotNil(err)
+}
+
+func TestFormatMatch(t *testing.T) {
+	assert := assert.New(t)
+	c, _ := algorithm.CrcByName("CRC-16/MODBUS")
+	assert.Equal(formatMatch(algorithm.CrcMatch{Config: c, ByteOrder: binary.LittleEndian}),
+		"width=16 poly=0x8005 init=0xffff refin=true refout=true "+
+			"xorout=0x0000 check=0x4b37 residue=0x0000 order=little-endian "+
+			`name="CRC-16/MODBUS"`)
+	c = algorithm.Config{Width: 5, Polynomial: 0x05}
+	assert.Equal(formatMatch(algorithm.CrcMatch{Config: c, ByteOrder: binary.BigEndian}),
+		"width=5 poly=0x05 init=0x00 refin=false refout=false "+
+			"xorout=0x00 check=0x00 residue=0x00 order=big-endian "+
+			`name="(none)"`)
+}
@@ -69,16 +69,33 @@ func (a *Alarm) SetRange(newRange Range) *Alarm {
 	return a
 }
 
-func (a *Alarm) OnAlarm(f func(Number, *Alarm)) *Alarm {
-	a.sigAlarm.Connect(f)
+// OnAlarm connects f and returns a, so the calls can be chained. Use
+// SignalAlarm().Connect to get the Connection of f.
+func (a *Alarm) OnAlarm(f func(Number, *Alarm),
+	opts ...ConnectOption) *Alarm {
+	a.sigAlarm.Connect(f, opts...)
 	return a
 }
 
-func (a *Alarm) OnAlarmCanceled(f func(Number, *Alarm)) *Alarm {
-	a.sigCanceled.Connect(f)
+// OnAlarmCanceled is like OnAlarm, use SignalCanceled().Connect to get the
+// Connection of f
+func (a *Alarm) OnAlarmCanceled(f func(Number, *Alarm),
+	opts ...ConnectOption) *Alarm {
+	a.sigCanceled.Connect(f, opts...)
 	return a
 }
 
+// SignalAlarm returns the signal of OnAlarm, such as to connect a callback
+// with ConnectOnce
+func (a *Alarm) SignalAlarm() *SignalAlarm {
+	return &a.sigAlarm
+}
+
+// SignalCanceled returns the signal of OnAlarmCanceled
+func (a *Alarm) SignalCanceled() *SignalAlarm {
+	return &a.sigCanceled
+}
+
 func (a *Alarm) AlarmCount() uint32 {
 	return a.alarmCount
 }
@@ -101,8 +118,16 @@ func (a *Alarm) IsAlarming() bool {
 }
 
 func (a *Alarm) Check() {
+	if sig := a.check(); sig != nil {
+		sig.Fire(AlarmArgs{*a.dataPtr, a})
+	}
+}
+
+// check updates the state of a for the value of the data, it returns the
+// signal to fire or nil if the state did not change
+func (a *Alarm) check() *SignalAlarm {
 	if !a.enabled {
-		return
+		return nil
 	}
 	value := *a.dataPtr
 	if value < a.alarmRange.low || value > a.alarmRange.high {
@@ -112,12 +137,12 @@ func (a *Alarm) Check() {
 				a.state = UnderFlow
 				a.lastAlarmValue = value
 				a.lastAlarmTime = time.Now()
-				a.sigAlarm.fire(value, a)
+				return &a.sigAlarm
 			} else if value > a.alarmRange.high && a.state != OverFlow {
 				a.state = OverFlow
 				a.lastAlarmValue = value
 				a.lastAlarmTime = time.Now()
-				a.sigAlarm.fire(value, a)
+				return &a.sigAlarm
 			}
 		}
 	} else {
@@ -126,9 +151,10 @@ func (a *Alarm) Check() {
 			a.state = AutoCanceled
 			a.lastCancelValue = value
 			a.lastCancelTime = time.Now()
-			a.sigCanceled.fire(value, a)
+			return &a.sigCanceled
 		}
 	}
+	return nil
 }
 
 func (a *Alarm) CancelAlarm() {
@@ -136,7 +162,7 @@ func (a *Alarm) CancelAlarm() {
 	a.state = ManualCanceled
 	a.lastCancelValue = *a.dataPtr
 	a.lastCancelTime = time.Now()
-	a.sigCanceled.fire(*a.dataPtr, a)
+	a.sigCanceled.Fire(AlarmArgs{*a.dataPtr, a})
 }
 
 func (a *Alarm) LastAlarmValue() Number {
@@ -168,7 +194,7 @@ func (a *Alarm) SetEnable(en bool) {
 		a.state = AutoCanceled
 		a.lastCancelValue = *a.dataPtr
 		a.lastCancelTime = time.Now()
-		a.sigCanceled.fire(a.lastCancelValue, a)
+		a.sigCanceled.Fire(AlarmArgs{a.lastCancelValue, a})
 	}
 	a.enabled = en
 }
@@ -233,7 +259,7 @@ func (v *AlarmRecord) FromProtoMessage(p *types.SAlarmInfo) {
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface.
 func (a *AlarmRecord) MarshalBinary() (data []byte, err error) {
-	defer SetErrorWhenMarshalObjectErrorPanic("common.AlarmRecord", &err)
+	defer SetErrorWhenMarshalObjectErrorPanic("common.AlarmRecord", &err)()
 	return MarshalProtoMessage(a.ToProtoMessage())
 }
 
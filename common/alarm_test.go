@@ -188,6 +188,16 @@ func TestAlarmOnAlarm(t *testing.T) {
 	d.SetValue(5)
 	loopCheck(&a, 4)
 	assert.EqualValues(i, 19)
+
+	c := a.SignalAlarm().Connect(func(common.Number, *common.Alarm) {
+		i = 0
+	}, common.WithPriority(1))
+	d.SetValue(15)
+	a.Check()
+	c.Disconnect()
+	d.SetValue(5)
+	loopCheck(&a, 4)
+	assert.EqualValues(i, 199)
 }
 
 func TestAlarmOnAlarmCanceled(t *testing.T) {
@@ -335,3 +345,17 @@ func TestAlarmRecordMarshalBinary(t *testing.T) {
 	assert.EqualValues(r2.Bound, 100)
 	assert.EqualValues(r2.Time, time.Now().Truncate(time.Hour))
 }
+
+func FuzzAlarmRecordUnmarshalBinary(f *testing.F) {
+	data, _ := (&common.AlarmRecord{DataId: "aaa", State: common.UnderFlow,
+		Value: -100, Bound: 100, Time: time.Unix(0, 0)}).MarshalBinary()
+	f.Add(data)
+	f.Add([]byte{0x00, 0x01, 0xC0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var r common.AlarmRecord
+		n, err := r.UnmarshalBinaryWithSize(data)
+		if err == nil && (n < 0 || n > len(data)) {
+			t.Fatalf("consumed %d of %d bytes", n, len(data))
+		}
+	})
+}
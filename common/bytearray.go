@@ -2,6 +2,7 @@ package common
 
 import (
 	"bytes"
+	"encoding/binary"
 	"github.com/newkedison/go-utils/algorithm"
 	"github.com/newkedison/go-utils/internal/types"
 	"strconv"
@@ -71,10 +72,37 @@ func (ba *ByteArray) AppendString(s string) {
 	*ba = append(*ba, []byte(s)...)
 }
 
+// AddCrc appends the CRC calculated by c
+func (ba *ByteArray) AddCrc(c *algorithm.Crc) {
+	*ba = c.Append([]byte(*ba))
+}
+
+// VerifyCrc reports whether ba ends with the CRC calculated by c
+func (ba ByteArray) VerifyCrc(c *algorithm.Crc) bool {
+	return c.Verify([]byte(ba))
+}
+
+// AddCrc16 appends the CRC of algorithm.DefaultCrc16ConfigIndex, use AddCrc
+// when the format must not depend on the package default
 func (ba *ByteArray) AddCrc16() {
 	*ba = algorithm.AppendCrc16([]byte(*ba))
 }
 
+// AddCrcWithConfig appends the CRC of config with the given byte order
+func (ba *ByteArray) AddCrcWithConfig(config algorithm.Config,
+	order binary.ByteOrder) error {
+	data, err := algorithm.AppendCrcWithConfig([]byte(*ba), config, order)
+	*ba = ByteArray(data)
+	return err
+}
+
+// VerifyCrcWithConfig reports whether ba ends with the CRC of the bytes
+// before it, see AddCrcWithConfig
+func (ba ByteArray) VerifyCrcWithConfig(config algorithm.Config,
+	order binary.ByteOrder) (bool, error) {
+	return algorithm.VerifyCrcWithConfig([]byte(ba), config, order)
+}
+
 func (ba ByteArray) Crc8() byte {
 	return algorithm.Crc8([]byte(ba))
 }
@@ -87,6 +115,62 @@ func (ba ByteArray) Crc32() uint32 {
 	return algorithm.Crc32([]byte(ba))
 }
 
+func (ba *ByteArray) AddAdler32() {
+	*ba = algorithm.AppendAdler32([]byte(*ba))
+}
+
+func (ba ByteArray) Adler32() uint32 {
+	return algorithm.Adler32([]byte(ba))
+}
+
+func (ba *ByteArray) AddFletcher16() {
+	*ba = algorithm.AppendFletcher16([]byte(*ba))
+}
+
+func (ba ByteArray) Fletcher16() uint16 {
+	return algorithm.Fletcher16([]byte(ba))
+}
+
+func (ba *ByteArray) AddFletcher32() {
+	*ba = algorithm.AppendFletcher32([]byte(*ba))
+}
+
+func (ba ByteArray) Fletcher32() uint32 {
+	return algorithm.Fletcher32([]byte(ba))
+}
+
+func (ba *ByteArray) AddLrc() {
+	*ba = algorithm.AppendLrc([]byte(*ba))
+}
+
+func (ba ByteArray) Lrc() byte {
+	return algorithm.Lrc([]byte(ba))
+}
+
+func (ba *ByteArray) AddXor8() {
+	*ba = algorithm.AppendXor8([]byte(*ba))
+}
+
+func (ba ByteArray) Xor8() byte {
+	return algorithm.Xor8([]byte(ba))
+}
+
+func (ba *ByteArray) AddSum8() {
+	*ba = algorithm.AppendSum8([]byte(*ba))
+}
+
+func (ba ByteArray) Sum8() byte {
+	return algorithm.Sum8([]byte(ba))
+}
+
+func (ba *ByteArray) AddSum16() {
+	*ba = algorithm.AppendSum16([]byte(*ba))
+}
+
+func (ba ByteArray) Sum16() uint16 {
+	return algorithm.Sum16([]byte(ba))
+}
+
 func (ba ByteArray) Clone() ByteArray {
 	return append(ByteArray{}, ba...)
 }
@@ -1,7 +1,9 @@
 package common_test
 
 import (
+	"encoding/binary"
 	"errors"
+	"github.com/newkedison/go-utils/algorithm"
 	"github.com/newkedison/go-utils/common"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -112,6 +114,64 @@ func TestAddCrc16(t *testing.T) {
 	assert.Equal(ba.ToString(), "[5]10 BE 8C 00 00")
 }
 
+func TestAddCrcWithConfig(t *testing.T) {
+	assert := assert.New(t)
+	xmodem, _ := algorithm.CrcByName("CRC-16/XMODEM")
+	ba := common.ByteArray("123456789")
+	assert.Nil(ba.AddCrcWithConfig(xmodem, binary.BigEndian))
+	assert.Equal(ba[9:].ToString(), "[2]31 C3")
+	ok, err := ba.VerifyCrcWithConfig(xmodem, binary.BigEndian)
+	assert.True(ok)
+	assert.Nil(err)
+	ok, _ = ba.VerifyCrcWithConfig(xmodem, binary.LittleEndian)
+	assert.False(ok)
+	ba = common.ByteArray{}
+	assert.NotNil(ba.AddCrcWithConfig(algorithm.Config{}, binary.BigEndian))
+	assert.Equal(len(ba), 0)
+}
+
+func TestAddCrc(t *testing.T) {
+	assert := assert.New(t)
+	c, _ := algorithm.NewCrcByName("CRC-16/XMODEM")
+	ba := common.ByteArray("123456789")
+	ba.AddCrc(c.WithByteOrder(binary.BigEndian))
+	assert.Equal(ba[9:].ToString(), "[2]31 C3")
+	assert.True(ba.VerifyCrc(c.WithByteOrder(binary.BigEndian)))
+	assert.False(ba.VerifyCrc(c))
+}
+
+func TestChecksum(t *testing.T) {
+	assert := assert.New(t)
+	ba := common.ByteArray("abcde")
+	assert.EqualValues(ba.Adler32(), 0x05C801F0)
+	assert.EqualValues(ba.Fletcher16(), 0xC8F0)
+	assert.EqualValues(ba.Fletcher32(), 0xF04FC729)
+	assert.EqualValues(ba.Lrc(), 0x11)
+	assert.EqualValues(ba.Xor8(), 0x61)
+	assert.EqualValues(ba.Sum8(), 0xEF)
+	assert.EqualValues(ba.Sum16(), 0x01EF)
+	ba.AddAdler32()
+	assert.Equal(ba.ToString(), "[9]61 62 63 64 65 05 C8 01 F0")
+	ba = common.ByteArray("abcde")
+	ba.AddFletcher16()
+	assert.Equal(ba.ToString(), "[7]61 62 63 64 65 F0 C8")
+	ba = common.ByteArray("abcde")
+	ba.AddFletcher32()
+	assert.Equal(ba.ToString(), "[9]61 62 63 64 65 29 C7 4F F0")
+	ba = common.ByteArray("abcde")
+	ba.AddLrc()
+	assert.Equal(ba.ToString(), "[6]61 62 63 64 65 11")
+	ba = common.ByteArray("abcde")
+	ba.AddXor8()
+	assert.Equal(ba.ToString(), "[6]61 62 63 64 65 61")
+	ba = common.ByteArray("abcde")
+	ba.AddSum8()
+	assert.Equal(ba.ToString(), "[6]61 62 63 64 65 EF")
+	ba = common.ByteArray("abcde")
+	ba.AddSum16()
+	assert.Equal(ba.ToString(), "[7]61 62 63 64 65 EF 01")
+}
+
 func TestCrc8(t *testing.T) {
 	assert := assert.New(t)
 	assert.EqualValues(common.ByteArray{0x10}.Crc8(), 0x70)
@@ -188,3 +248,16 @@ func TestUnmarshalBinary(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(ba, ba2)
 }
+
+func FuzzByteArrayUnmarshalBinary(f *testing.F) {
+	data, _ := common.NewByteArray([]byte{0x01, 0x02}).MarshalBinary()
+	f.Add(data)
+	f.Add([]byte{0x01, 0x00, 0x00, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var ba common.ByteArray
+		n, err := ba.UnmarshalBinaryWithSize(data)
+		if err == nil && (n < 0 || n > len(data)) {
+			t.Fatalf("consumed %d of %d bytes", n, len(data))
+		}
+	})
+}
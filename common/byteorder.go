@@ -0,0 +1,75 @@
+package common
+
+import (
+	"encoding/binary"
+)
+
+// Mixed-endian orders used by Modbus devices for 32 and 64 bits registers,
+// the bytes inside a 16 bits word keep their order but the words are
+// swapped. For 0x11223344:
+//
+//	BigEndianWordSwap     33 44 11 22 (CDAB)
+//	LittleEndianWordSwap  22 11 44 33 (BADC)
+var (
+	BigEndianWordSwap binary.ByteOrder = wordSwapOrder{
+		word: binary.BigEndian, lowWordFirst: true, name: "BigEndianWordSwap"}
+	LittleEndianWordSwap binary.ByteOrder = wordSwapOrder{
+		word: binary.LittleEndian, name: "LittleEndianWordSwap"}
+)
+
+type wordSwapOrder struct {
+	// word is the byte order inside a 16 bits word
+	word         binary.ByteOrder
+	lowWordFirst bool
+	name         string
+}
+
+func (o wordSwapOrder) Uint16(b []byte) uint16 {
+	return o.word.Uint16(b)
+}
+
+func (o wordSwapOrder) PutUint16(b []byte, v uint16) {
+	o.word.PutUint16(b, v)
+}
+
+func (o wordSwapOrder) Uint32(b []byte) uint32 {
+	_ = b[3] // bounds check hint to compiler
+	hi, lo := o.word.Uint16(b), o.word.Uint16(b[2:])
+	if o.lowWordFirst {
+		hi, lo = lo, hi
+	}
+	return uint32(hi)<<16 | uint32(lo)
+}
+
+func (o wordSwapOrder) PutUint32(b []byte, v uint32) {
+	_ = b[3] // early bounds check to guarantee safety of writes below
+	hi, lo := b, b[2:]
+	if o.lowWordFirst {
+		hi, lo = lo, hi
+	}
+	o.word.PutUint16(hi, uint16(v>>16))
+	o.word.PutUint16(lo, uint16(v))
+}
+
+func (o wordSwapOrder) Uint64(b []byte) uint64 {
+	_ = b[7] // bounds check hint to compiler
+	hi, lo := o.Uint32(b), o.Uint32(b[4:])
+	if o.lowWordFirst {
+		hi, lo = lo, hi
+	}
+	return uint64(hi)<<32 | uint64(lo)
+}
+
+func (o wordSwapOrder) PutUint64(b []byte, v uint64) {
+	_ = b[7] // early bounds check to guarantee safety of writes below
+	hi, lo := b, b[4:]
+	if o.lowWordFirst {
+		hi, lo = lo, hi
+	}
+	o.PutUint32(hi, uint32(v>>32))
+	o.PutUint32(lo, uint32(v))
+}
+
+func (o wordSwapOrder) String() string {
+	return o.name
+}
@@ -0,0 +1,44 @@
+package common_test
+
+import (
+	"encoding/binary"
+	"github.com/newkedison/go-utils/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWordSwapOrder(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		order binary.ByteOrder
+		name  string
+		b16   []byte
+		b32   []byte
+		b64   []byte
+	}{
+		{common.BigEndianWordSwap, "BigEndianWordSwap",
+			[]byte{0x11, 0x22},
+			[]byte{0x33, 0x44, 0x11, 0x22},
+			[]byte{0x77, 0x88, 0x55, 0x66, 0x33, 0x44, 0x11, 0x22}},
+		{common.LittleEndianWordSwap, "LittleEndianWordSwap",
+			[]byte{0x22, 0x11},
+			[]byte{0x22, 0x11, 0x44, 0x33},
+			[]byte{0x22, 0x11, 0x44, 0x33, 0x66, 0x55, 0x88, 0x77}},
+	}
+	for _, c := range cases {
+		buf := make([]byte, 8)
+		c.order.PutUint16(buf, 0x1122)
+		assert.Equal(buf[:2], c.b16, c.name)
+		assert.EqualValues(c.order.Uint16(c.b16), 0x1122, c.name)
+		c.order.PutUint32(buf, 0x11223344)
+		assert.Equal(buf[:4], c.b32, c.name)
+		assert.EqualValues(c.order.Uint32(c.b32), 0x11223344, c.name)
+		c.order.PutUint64(buf, 0x1122334455667788)
+		assert.Equal(buf, c.b64, c.name)
+		assert.EqualValues(c.order.Uint64(c.b64), uint64(0x1122334455667788),
+			c.name)
+		assert.Equal(c.order.String(), c.name)
+		assert.Panics(func() { c.order.Uint32(buf[:3]) })
+		assert.Panics(func() { c.order.PutUint64(buf[:7], 0) })
+	}
+}
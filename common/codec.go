@@ -0,0 +1,739 @@
+package common
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Marshal and Unmarshal walk structs by reflection, fields are encoded in
+// order with the layout of MarshalSimpleType unless the `bin` tag says
+// otherwise, but slices and strings are prefixed by a u16 length like
+// MarshalString, where MarshalSimpleType writes a PackedLength. The tag is a
+// comma separated list of:
+//
+//	u8 u16 u32 u64 i8 i16 i32 i64 f32 f64
+//	               wire type of a number, or of the elements of an array/slice
+//	be, le         byte order of the field, inherited by nested structs
+//	string         marks a string field
+//	len=Count      the length of a slice or string is held by the integer
+//	               field Count declared before it, Marshal sets it
+//	lenprefix=u8   type of the length prefix, u16 by default as MarshalString
+//	skip=2         padding bytes before the field
+//	-              the field is ignored
+//
+// Nested structs, pointers, fixed arrays, slices, bool (one byte), time.Time
+// and time.Duration (i64 count of DefaultTimeUnit, or of the time unit of the
+// Encoder or Decoder) and types implementing both encoding.BinaryMarshaler
+// and BinaryUnmarshalerWithSize are supported. Unexported fields are
+// ignored, blank fields (named _) are written as zeros and skipped when
+// reading.
+
+type wireType int
+
+const (
+	wireNone wireType = iota
+	wireU8
+	wireU16
+	wireU32
+	wireU64
+	wireI8
+	wireI16
+	wireI32
+	wireI64
+	wireF32
+	wireF64
+)
+
+var wireTypeNames = []string{
+	"", "u8", "u16", "u32", "u64", "i8", "i16", "i32", "i64", "f32", "f64"}
+
+func parseWireType(s string) (wireType, bool) {
+	for i, name := range wireTypeNames {
+		if i > 0 && name == s {
+			return wireType(i), true
+		}
+	}
+	return wireNone, false
+}
+
+func (w wireType) String() string {
+	return wireTypeNames[w]
+}
+
+func (w wireType) size() int {
+	switch w {
+	case wireU8, wireI8:
+		return 1
+	case wireU16, wireI16:
+		return 2
+	case wireU32, wireI32, wireF32:
+		return 4
+	}
+	return 8
+}
+
+func (w wireType) signed() bool {
+	return w >= wireI8 && w <= wireI64
+}
+
+func (w wireType) float() bool {
+	return w == wireF32 || w == wireF64
+}
+
+// defaultWireType returns the wire type used by MarshalSimpleType for kind
+func defaultWireType(kind reflect.Kind) wireType {
+	switch kind {
+	case reflect.Bool, reflect.Uint8:
+		return wireU8
+	case reflect.Int8:
+		return wireI8
+	case reflect.Int16:
+		return wireI16
+	case reflect.Uint16:
+		return wireU16
+	case reflect.Int, reflect.Int32:
+		return wireI32
+	case reflect.Uint, reflect.Uint32:
+		return wireU32
+	case reflect.Int64:
+		return wireI64
+	case reflect.Uint64:
+		return wireU64
+	case reflect.Float32:
+		return wireF32
+	case reflect.Float64:
+		return wireF64
+	}
+	return wireNone
+}
+
+func putWire(buf []byte, w wireType, bits uint64,
+	order binary.ByteOrder) []byte {
+	var tmp [8]byte
+	switch w.size() {
+	case 1:
+		tmp[0] = byte(bits)
+	case 2:
+		order.PutUint16(tmp[:], uint16(bits))
+	case 4:
+		order.PutUint32(tmp[:], uint32(bits))
+	default:
+		order.PutUint64(tmp[:], bits)
+	}
+	return append(buf, tmp[:w.size()]...)
+}
+
+func getWire(data []byte, w wireType, order binary.ByteOrder) uint64 {
+	switch w.size() {
+	case 1:
+		return uint64(data[0])
+	case 2:
+		return uint64(order.Uint16(data))
+	case 4:
+		return uint64(order.Uint32(data))
+	}
+	return order.Uint64(data)
+}
+
+type binOptions struct {
+	wire  wireType
+	order binary.ByteOrder
+	// lenField is the index of the field holding the length, -1 if none
+	lenField  int
+	lenPrefix wireType
+	skip      int
+}
+
+type fieldCodec struct {
+	binOptions
+	index int
+	blank bool
+	// lengthOf are the indexes of the fields whose length this field holds
+	lengthOf []int
+}
+
+type structCodec struct {
+	fields []fieldCodec
+	err    error
+}
+
+// structCodecCache maps reflect.Type to *structCodec, the tags of a type are
+// parsed only once
+var structCodecCache sync.Map
+
+func getStructCodec(t reflect.Type) *structCodec {
+	if v, ok := structCodecCache.Load(t); ok {
+		return v.(*structCodec)
+	}
+	v, _ := structCodecCache.LoadOrStore(t, newStructCodec(t))
+	return v.(*structCodec)
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Uint64
+}
+
+func newStructCodec(t reflect.Type) *structCodec {
+	c := &structCodec{}
+	positions := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("bin")
+		blank := sf.Name == "_"
+		if tag == "-" || (sf.PkgPath != "" && !blank) {
+			continue
+		}
+		f := fieldCodec{
+			binOptions: binOptions{lenField: -1}, index: i, blank: blank}
+		lenName, err := parseBinTag(tag, &f.binOptions)
+		if err == nil && lenName != "" {
+			err = c.linkLength(&f, sf, lenName, positions, t)
+		}
+		if err != nil {
+			c.err = fmt.Errorf("field %s: %v", sf.Name, err)
+			return c
+		}
+		positions[sf.Name] = len(c.fields)
+		c.fields = append(c.fields, f)
+	}
+	return c
+}
+
+func (c *structCodec) linkLength(f *fieldCodec, sf reflect.StructField,
+	lenName string, positions map[string]int, t reflect.Type) error {
+	kind := sf.Type.Kind()
+	if kind != reflect.Slice && kind != reflect.String {
+		return errors.New("len is only valid for slice and string")
+	}
+	pos, ok := positions[lenName]
+	if !ok {
+		return fmt.Errorf("length field %s is not declared before", lenName)
+	}
+	holder := &c.fields[pos]
+	if !isIntegerKind(t.Field(holder.index).Type.Kind()) {
+		return fmt.Errorf("length field %s is not an integer", lenName)
+	}
+	f.lenField = holder.index
+	holder.lengthOf = append(holder.lengthOf, f.index)
+	return nil
+}
+
+func parseBinTag(tag string, o *binOptions) (lenName string, err error) {
+	if tag == "" {
+		return "", nil
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		key, value := opt, ""
+		if i := strings.Index(opt, "="); i >= 0 {
+			key, value = opt[:i], opt[i+1:]
+		}
+		switch key {
+		case "be":
+			o.order = binary.BigEndian
+		case "le":
+			o.order = binary.LittleEndian
+		case "string":
+		case "len":
+			if value == "" {
+				return "", errors.New("empty len")
+			}
+			lenName = value
+		case "lenprefix":
+			w, ok := parseWireType(value)
+			if !ok || w.float() {
+				return "", fmt.Errorf("invalid lenprefix %q", value)
+			}
+			o.lenPrefix = w
+		case "skip":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return "", fmt.Errorf("invalid skip %q", value)
+			}
+			o.skip = n
+		default:
+			w, ok := parseWireType(key)
+			if !ok || value != "" {
+				return "", fmt.Errorf("unknown option %q", opt)
+			}
+			o.wire = w
+		}
+	}
+	return lenName, nil
+}
+
+var (
+	binaryMarshalerType = reflect.TypeOf(
+		(*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf(
+		(*BinaryUnmarshalerWithSize)(nil)).Elem()
+	byteType     = reflect.TypeOf(byte(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// isBinaryObject reports whether t is handled by MarshalObject and
+// UnmarshalObject
+func isBinaryObject(t reflect.Type) bool {
+	p := reflect.PtrTo(t)
+	return p.Implements(binaryMarshalerType) &&
+		p.Implements(binaryUnmarshalerType)
+}
+
+// isByteElement reports whether the elements of t are copied as raw bytes
+func isByteElement(t reflect.Type, o binOptions) bool {
+	return t.Elem() == byteType && (o.wire == wireNone || o.wire == wireU8)
+}
+
+func elementOptions(o binOptions) binOptions {
+	return binOptions{wire: o.wire, lenField: -1, lenPrefix: o.lenPrefix}
+}
+
+// codecError is the panic of the codec, other panics such as runtime errors
+// are not recovered by recoverCodecError
+type codecError struct {
+	err error
+}
+
+// recoverCodecError is like SetErrorWhenMarshalObjectErrorPanic, but the
+// error is wrapped so errors.Is and errors.As see the cause
+func recoverCodecError(op string, typeName string, err *error) {
+	if r := recover(); r != nil {
+		e, ok := r.(codecError)
+		if !ok {
+			panic(r)
+		}
+		*err = fmt.Errorf("%s %s fail: %w", op, typeName, e.err)
+	}
+}
+
+// checkCodecError stops Marshal or Unmarshal with err if it is not nil
+func checkCodecError(err error) {
+	if err != nil {
+		panic(codecError{err})
+	}
+}
+
+func marshalError(format string, args ...interface{}) {
+	panic(codecError{fmt.Errorf(format, args...)})
+}
+
+func unmarshalError(format string, args ...interface{}) {
+	panic(codecError{fmt.Errorf(format, args...)})
+}
+
+type encoder struct {
+	buf      []byte
+	timeUnit time.Duration
+}
+
+// Marshal encodes v as described by the `bin` tags of its fields, with the
+// byte order set by SetByteOrder. A slice field has a u16 length prefix by
+// default, not the PackedLength of MarshalSimpleType.
+func Marshal(v interface{}) ([]byte, error) {
+	return marshal(v, defaultByteOrder, DefaultTimeUnit)
+}
+
+func marshal(v interface{}, order binary.ByteOrder,
+	timeUnit time.Duration) (_ []byte, err error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil, errors.New("Marshal: nil value")
+	}
+	defer recoverCodecError("Marshal", rv.Type().String(), &err)
+	// an addressable copy, so methods with pointer receiver can be called
+	p := reflect.New(rv.Type())
+	p.Elem().Set(rv)
+	e := encoder{timeUnit: timeUnit}
+	e.encode(p.Elem(), binOptions{lenField: -1}, order)
+	return e.buf, nil
+}
+
+func (e *encoder) encode(v reflect.Value, o binOptions,
+	order binary.ByteOrder) {
+	if o.order != nil {
+		order = o.order
+	}
+	if isBinaryObject(v.Type()) {
+		obj := v.Addr().Interface().(encoding.BinaryMarshaler)
+		data, err := EncodeObject(obj)
+		checkCodecError(err)
+		e.buf = append(e.buf, data...)
+		return
+	}
+	switch v.Type() {
+	case timeType:
+		units, err := timeToUnits(v.Interface().(time.Time), e.timeUnit)
+		checkCodecError(err)
+		e.encodeNumber(reflect.ValueOf(units), o.wire, order)
+		return
+	case durationType:
+		units := v.Interface().(time.Duration) / e.timeUnit
+		e.encodeNumber(reflect.ValueOf(int64(units)), o.wire, order)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		e.encodeNumber(v, o.wire, order)
+	case reflect.String:
+		if o.lenField < 0 {
+			e.encodeLength(v.Len(), o.lenPrefix, order)
+		}
+		e.buf = append(e.buf, v.String()...)
+	case reflect.Slice:
+		if o.lenField < 0 {
+			e.encodeLength(v.Len(), o.lenPrefix, order)
+		}
+		e.encodeElements(v, o, order)
+	case reflect.Array:
+		e.encodeElements(v, o, order)
+	case reflect.Struct:
+		e.encodeStruct(v, order)
+	case reflect.Ptr:
+		if v.IsNil() {
+			marshalError("nil pointer %s", v.Type())
+		}
+		e.encode(v.Elem(), o, order)
+	default:
+		marshalError("%w %s", ErrUnknownType, v.Type())
+	}
+}
+
+func (e *encoder) encodeNumber(v reflect.Value, w wireType,
+	order binary.ByteOrder) {
+	if w == wireNone {
+		w = defaultWireType(v.Kind())
+	}
+	kind := v.Kind()
+	if (kind == reflect.Float32 || kind == reflect.Float64) != w.float() {
+		marshalError("%s can not be encoded as %s", v.Type(), w)
+	}
+	var bits uint64
+	switch kind {
+	case reflect.Float32, reflect.Float64:
+		if w == wireF32 {
+			bits = uint64(math.Float32bits(float32(v.Float())))
+		} else {
+			bits = math.Float64bits(v.Float())
+		}
+	case reflect.Bool:
+		if v.Bool() {
+			bits = 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits = encodeSigned(v.Int(), w)
+	default:
+		bits = encodeUnsigned(v.Uint(), w)
+	}
+	e.buf = putWire(e.buf, w, bits, order)
+}
+
+func encodeSigned(i int64, w wireType) uint64 {
+	n := uint(w.size() * 8)
+	if w.signed() {
+		if n < 64 && (i < -1<<(n-1) || i > 1<<(n-1)-1) {
+			marshalError("value %d overflows %s", i, w)
+		}
+	} else if i < 0 || (n < 64 && uint64(i) > 1<<n-1) {
+		marshalError("value %d overflows %s", i, w)
+	}
+	return uint64(i)
+}
+
+func encodeUnsigned(u uint64, w wireType) uint64 {
+	n := uint(w.size() * 8)
+	if w.signed() {
+		n--
+	}
+	if n < 64 && u > 1<<n-1 {
+		marshalError("value %d overflows %s", u, w)
+	}
+	return u
+}
+
+func (e *encoder) encodeLength(n int, w wireType, order binary.ByteOrder) {
+	if w == wireNone {
+		w = wireU16
+	}
+	if size := uint(w.size() * 8); size < 64 && uint64(n) > 1<<size-1 {
+		marshalError("length %d overflows %s", n, w)
+	}
+	e.buf = putWire(e.buf, w, uint64(n), order)
+}
+
+func (e *encoder) encodeElements(v reflect.Value, o binOptions,
+	order binary.ByteOrder) {
+	if v.Kind() == reflect.Slice && isByteElement(v.Type(), o) {
+		e.buf = append(e.buf, v.Bytes()...)
+		return
+	}
+	elem := elementOptions(o)
+	for i := 0; i < v.Len(); i++ {
+		e.encode(v.Index(i), elem, order)
+	}
+}
+
+func (e *encoder) encodeStruct(v reflect.Value, order binary.ByteOrder) {
+	c := getStructCodec(v.Type())
+	if c.err != nil {
+		marshalError("%s: %v", v.Type(), c.err)
+	}
+	for i := range c.fields {
+		f := &c.fields[i]
+		for k := 0; k < f.skip; k++ {
+			e.buf = append(e.buf, 0)
+		}
+		fv := v.Field(f.index)
+		if f.blank {
+			fv = reflect.New(fv.Type()).Elem()
+		}
+		if len(f.lengthOf) > 0 {
+			fv = lengthValue(v, f, fv.Type())
+		}
+		e.encode(fv, f.binOptions, order)
+	}
+}
+
+// lengthValue returns the value written for a length field, the length of
+// the fields referring to it
+func lengthValue(v reflect.Value, f *fieldCodec,
+	t reflect.Type) reflect.Value {
+	n := v.Field(f.lengthOf[0]).Len()
+	for _, j := range f.lengthOf[1:] {
+		if v.Field(j).Len() != n {
+			marshalError("fields with length %s have different length",
+				v.Type().Field(f.index).Name)
+		}
+	}
+	lv := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if lv.OverflowInt(int64(n)) {
+			marshalError("length %d overflows %s", n, t)
+		}
+		lv.SetInt(int64(n))
+	default:
+		if lv.OverflowUint(uint64(n)) {
+			marshalError("length %d overflows %s", n, t)
+		}
+		lv.SetUint(uint64(n))
+	}
+	return lv
+}
+
+type decoder struct {
+	data     []byte
+	offset   int
+	timeUnit time.Duration
+}
+
+// need stops Unmarshal with a *ShortBufferError if there are less than n
+// bytes left
+func (d *decoder) need(n int) {
+	checkCodecError(CheckBuffer(d.data, n, d.offset))
+}
+
+// Unmarshal decodes data into v, which must be a non-nil pointer, and
+// returns the number of bytes used
+func Unmarshal(data []byte, v interface{}) (int, error) {
+	return unmarshal(data, v, defaultByteOrder, DefaultTimeUnit)
+}
+
+func unmarshal(data []byte, v interface{}, order binary.ByteOrder,
+	timeUnit time.Duration) (_ int, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return 0, errors.New("Unmarshal: v must be a non-nil pointer")
+	}
+	defer recoverCodecError("Unmarshal", rv.Type().Elem().String(), &err)
+	d := decoder{data: data, timeUnit: timeUnit}
+	d.decode(rv.Elem(), binOptions{lenField: -1}, order, -1)
+	return d.offset, nil
+}
+
+// decode reads v from the current offset, length is the number of elements
+// of a slice or string given by its length field, -1 if it has a prefix
+func (d *decoder) decode(v reflect.Value, o binOptions,
+	order binary.ByteOrder, length int) {
+	if o.order != nil {
+		order = o.order
+	}
+	if isBinaryObject(v.Type()) {
+		obj := v.Addr().Interface().(BinaryUnmarshalerWithSize)
+		n, err := DecodeObject(obj, d.data[d.offset:])
+		checkCodecError(err)
+		d.offset += n
+		return
+	}
+	if t := v.Type(); t == timeType || t == durationType {
+		var units int64
+		d.decodeNumber(reflect.ValueOf(&units).Elem(), o.wire, order)
+		if t == timeType {
+			v.Set(reflect.ValueOf(unitsToTime(units, d.timeUnit)))
+		} else {
+			v.SetInt(units * int64(d.timeUnit))
+		}
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		d.decodeNumber(v, o.wire, order)
+	case reflect.String:
+		if length < 0 {
+			length = d.decodeLength(o.lenPrefix, order)
+		}
+		d.need(length)
+		v.SetString(string(d.data[d.offset : d.offset+length]))
+		d.offset += length
+	case reflect.Slice:
+		if length < 0 {
+			length = d.decodeLength(o.lenPrefix, order)
+		}
+		if v.Type().Elem().Size() > 0 {
+			// each element needs at least one byte, reject huge lengths
+			// before allocating
+			d.need(length)
+		}
+		v.Set(reflect.MakeSlice(v.Type(), length, length))
+		d.decodeElements(v, o, order)
+	case reflect.Array:
+		d.decodeElements(v, o, order)
+	case reflect.Struct:
+		d.decodeStruct(v, order)
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		d.decode(v.Elem(), o, order, length)
+	default:
+		unmarshalError("%w %s", ErrUnknownType, v.Type())
+	}
+}
+
+func (d *decoder) decodeNumber(v reflect.Value, w wireType,
+	order binary.ByteOrder) {
+	if w == wireNone {
+		w = defaultWireType(v.Kind())
+	}
+	kind := v.Kind()
+	if (kind == reflect.Float32 || kind == reflect.Float64) != w.float() {
+		unmarshalError("%s can not be decoded from %s", v.Type(), w)
+	}
+	size := w.size()
+	d.need(size)
+	bits := getWire(d.data[d.offset:], w, order)
+	d.offset += size
+	var i int64
+	if w.signed() {
+		shift := uint(64 - size*8)
+		i = int64(bits<<shift) >> shift
+	}
+	switch kind {
+	case reflect.Float32, reflect.Float64:
+		if w == wireF32 {
+			v.SetFloat(float64(math.Float32frombits(uint32(bits))))
+		} else {
+			v.SetFloat(math.Float64frombits(bits))
+		}
+	case reflect.Bool:
+		v.SetBool(bits != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !w.signed() {
+			if bits > math.MaxInt64 {
+				unmarshalError("value %d overflows %s", bits, v.Type())
+			}
+			i = int64(bits)
+		}
+		if v.OverflowInt(i) {
+			unmarshalError("value %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+	default:
+		if w.signed() {
+			if i < 0 {
+				unmarshalError("value %d overflows %s", i, v.Type())
+			}
+			bits = uint64(i)
+		}
+		if v.OverflowUint(bits) {
+			unmarshalError("value %d overflows %s", bits, v.Type())
+		}
+		v.SetUint(bits)
+	}
+}
+
+func (d *decoder) decodeLength(w wireType, order binary.ByteOrder) int {
+	if w == wireNone {
+		w = wireU16
+	}
+	d.need(w.size())
+	n := getWire(d.data[d.offset:], w, order)
+	d.offset += w.size()
+	if int(n) < 0 || uint64(int(n)) != n {
+		unmarshalError("invalid length %d", n)
+	}
+	return int(n)
+}
+
+func (d *decoder) decodeElements(v reflect.Value, o binOptions,
+	order binary.ByteOrder) {
+	if isByteElement(v.Type(), o) {
+		d.need(v.Len())
+		reflect.Copy(v, reflect.ValueOf(d.data[d.offset:d.offset+v.Len()]))
+		d.offset += v.Len()
+		return
+	}
+	elem := elementOptions(o)
+	for i := 0; i < v.Len(); i++ {
+		d.decode(v.Index(i), elem, order, -1)
+	}
+}
+
+func (d *decoder) decodeStruct(v reflect.Value, order binary.ByteOrder) {
+	c := getStructCodec(v.Type())
+	if c.err != nil {
+		unmarshalError("%s: %v", v.Type(), c.err)
+	}
+	for i := range c.fields {
+		f := &c.fields[i]
+		d.need(f.skip)
+		d.offset += f.skip
+		fv := v.Field(f.index)
+		if f.blank {
+			fv = reflect.New(fv.Type()).Elem()
+		}
+		length := -1
+		if f.lenField >= 0 {
+			length = fieldLength(v.Field(f.lenField))
+		}
+		d.decode(fv, f.binOptions, order, length)
+	}
+}
+
+// fieldLength returns the value of a decoded length field
+func fieldLength(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 || int64(int(v.Int())) != v.Int() {
+			unmarshalError("invalid length %d", v.Int())
+		}
+		return int(v.Int())
+	}
+	n := v.Uint()
+	if int(n) < 0 || uint64(int(n)) != n {
+		unmarshalError("invalid length %d", n)
+	}
+	return int(n)
+}
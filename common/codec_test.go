@@ -0,0 +1,245 @@
+package common_test
+
+import (
+	"errors"
+	"github.com/newkedison/go-utils/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type codecHeader struct {
+	Magic uint16 `bin:"be"`
+	Flags byte
+}
+
+type codecFrame struct {
+	Header  codecHeader
+	Address int     `bin:"u8"`
+	Value   float32 `bin:"be"`
+	Valid   bool
+	Count   uint8
+	Items   []int16 `bin:"len=Count"`
+	Name    string  `bin:"string,lenprefix=u8"`
+	Raw     [3]byte `bin:"skip=2"`
+	_       [1]byte
+	Values  []uint32 `bin:"u16,lenprefix=u8"`
+	Payload []byte
+	Ignored int `bin:"-"`
+	Number  common.Number
+	Next    *codecHeader
+	hidden  int
+}
+
+func TestMarshalStruct(t *testing.T) {
+	assert := assert.New(t)
+	frame := codecFrame{
+		Header:  codecHeader{Magic: 0xAA55, Flags: 0x01},
+		Address: 0x10,
+		Value:   1.5,
+		Valid:   true,
+		Items:   []int16{1, -1},
+		Name:    "ab",
+		Raw:     [3]byte{1, 2, 3},
+		Values:  []uint32{1, 0x1234},
+		Payload: []byte{0xEE},
+		Ignored: 42,
+		Number:  1,
+		Next:    &codecHeader{Magic: 0x0102, Flags: 0x03},
+		hidden:  42,
+	}
+	buf, err := common.Marshal(frame)
+	assert.Nil(err)
+	expected := []byte{
+		0xAA, 0x55, 0x01, // Header
+		0x10,                   // Address
+		0x3F, 0xC0, 0x00, 0x00, // Value
+		0x01,                   // Valid
+		0x02,                   // Count
+		0x01, 0x00, 0xFF, 0xFF, // Items
+		0x02, 0x61, 0x62, // Name
+		0x00, 0x00, 0x01, 0x02, 0x03, // Raw
+		0x00,                         // _
+		0x02, 0x01, 0x00, 0x34, 0x12, // Values
+		0x01, 0x00, 0xEE, // Payload
+	}
+	expected = append(expected, common.MarshalObject(common.NewNumber(1))...)
+	expected = append(expected, 0x01, 0x02, 0x03)
+	assert.Equal(buf, expected)
+
+	var result codecFrame
+	n, err := common.Unmarshal(append(buf, 0x99), &result)
+	assert.Nil(err)
+	assert.Equal(n, len(buf))
+	frame.Count = 2
+	frame.Ignored = 0
+	frame.hidden = 0
+	assert.Equal(result, frame)
+
+	_, err = common.Unmarshal(buf[:len(buf)-1], &result)
+	assert.Contains(err.Error(),
+		"Unmarshal common_test.codecFrame fail: Not enought data, require 1")
+}
+
+func TestMarshalStructError(t *testing.T) {
+	assert := assert.New(t)
+	_, err := common.Marshal(nil)
+	assert.NotNil(err)
+	_, err = common.Marshal(struct {
+		A int `bin:"u8"`
+	}{300})
+	assert.EqualError(err,
+		"Marshal struct { A int \"bin:\\\"u8\\\"\" } fail: value 300 overflows u8")
+	_, err = common.Marshal(struct {
+		A int `bin:"u7"`
+	}{})
+	assert.Contains(err.Error(), `field A: unknown option "u7"`)
+	_, err = common.Marshal(struct {
+		A []byte `bin:"len=N"`
+		N int
+	}{})
+	assert.Contains(err.Error(),
+		"field A: length field N is not declared before")
+	_, err = common.Marshal(struct {
+		N string
+		A []byte `bin:"len=N"`
+	}{})
+	assert.Contains(err.Error(), "length field N is not an integer")
+	_, err = common.Marshal(struct {
+		A float32 `bin:"u8"`
+	}{})
+	assert.Contains(err.Error(), "float32 can not be encoded as u8")
+	_, err = common.Marshal(struct {
+		A []byte `bin:"lenprefix=u8"`
+	}{make([]byte, 256)})
+	assert.Contains(err.Error(), "length 256 overflows u8")
+	_, err = common.Marshal(struct{ A chan int }{})
+	assert.Contains(err.Error(), "Unknown type chan int")
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	_, err = common.Marshal(struct{ A *int }{})
+	assert.Contains(err.Error(), "nil pointer *int")
+	_, err = common.Marshal(struct {
+		N    uint8
+		A, B []byte `bin:"len=N"`
+	}{A: []byte{1}})
+	assert.Contains(err.Error(), "fields with length N have different length")
+
+	var v struct {
+		A uint16 `bin:"i8"`
+	}
+	_, err = common.Unmarshal([]byte{0xFF}, v)
+	assert.NotNil(err)
+	_, err = common.Unmarshal([]byte{0xFF}, &v)
+	assert.Contains(err.Error(), "value -1 overflows uint16")
+	var s struct {
+		A []uint16
+	}
+	_, err = common.Unmarshal([]byte{0xFF, 0xFF, 0x00}, &s)
+	assert.Contains(err.Error(), "Not enought data, require 65535")
+	var shortBuffer *common.ShortBufferError
+	if assert.True(errors.As(err, &shortBuffer)) {
+		assert.Equal(*shortBuffer, common.ShortBufferError{
+			Required: 65535, Offered: 3, Offset: 2})
+	}
+}
+
+// codecObject fails with err, or with a runtime error if err is nil
+type codecObject struct {
+	err error
+}
+
+func (o codecObject) MarshalBinary() ([]byte, error) {
+	if o.err == nil {
+		var p *int
+		return []byte{byte(*p)}, nil
+	}
+	return nil, o.err
+}
+
+func (o *codecObject) UnmarshalBinaryWithSize(data []byte) (int, error) {
+	if o.err == nil {
+		return int(data[len(data)]), nil
+	}
+	return 0, o.err
+}
+
+func TestMarshalObjectPanic(t *testing.T) {
+	assert := assert.New(t)
+	errObject := errors.New("object")
+	_, err := common.Marshal(struct{ A codecObject }{codecObject{errObject}})
+	assert.True(errors.Is(err, errObject))
+	v := struct{ A codecObject }{codecObject{errObject}}
+	_, err = common.Unmarshal(nil, &v)
+	assert.True(errors.Is(err, errObject))
+
+	// a runtime error is a bug, it is not returned as an error
+	assert.Panics(func() { common.Marshal(struct{ A codecObject }{}) })
+	assert.Panics(func() {
+		var v struct{ A codecObject }
+		common.Unmarshal(nil, &v)
+	})
+}
+
+func TestMarshalScalar(t *testing.T) {
+	assert := assert.New(t)
+	buf, err := common.Marshal(int16(-2))
+	assert.Nil(err)
+	assert.Equal(buf, common.MarshalSimpleType(int16(-2)))
+	var i int16
+	n, err := common.Unmarshal(buf, &i)
+	assert.Nil(err)
+	assert.Equal(n, 2)
+	assert.EqualValues(i, -2)
+	buf, err = common.Marshal([]string{"a", "bc"})
+	assert.Nil(err)
+	assert.Equal(buf,
+		[]byte{0x02, 0x00, 0x01, 0x00, 0x61, 0x02, 0x00, 0x62, 0x63})
+	var s []string
+	_, err = common.Unmarshal(buf, &s)
+	assert.Nil(err)
+	assert.Equal(s, []string{"a", "bc"})
+}
+
+func TestMarshalTime(t *testing.T) {
+	assert := assert.New(t)
+	type record struct {
+		Time    time.Time
+		Timeout time.Duration `bin:"u16,be"`
+	}
+	tm := time.Unix(1500000000, 123000000)
+	buf, err := common.Marshal(record{tm, 1500 * time.Millisecond})
+	assert.Nil(err)
+	assert.Equal(buf, append(common.MarshalSimpleType(tm), 0x05, 0xDC))
+	var result record
+	n, err := common.Unmarshal(buf, &result)
+	assert.Nil(err)
+	assert.Equal(n, 10)
+	assert.True(result.Time.Equal(tm))
+	assert.Equal(result.Timeout, 1500*time.Millisecond)
+	_, err = common.Marshal(record{Timeout: time.Minute * 2})
+	assert.Contains(err.Error(), "value 120000 overflows u16")
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	data, _ := common.Marshal(codecFrame{Count: 1, Items: []int16{1},
+		Name: "A", Next: &codecHeader{}})
+	f.Add(data)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var frame codecFrame
+		n, err := common.Unmarshal(data, &frame)
+		if err == nil && (n < 0 || n > len(data)) {
+			t.Fatalf("consumed %d of %d bytes", n, len(data))
+		}
+	})
+}
+
+func TestUnmarshalHugeLength(t *testing.T) {
+	assert := assert.New(t)
+	var v struct {
+		Items []int16 `bin:"lenprefix=u64"`
+	}
+	_, err := common.Unmarshal([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+		0x7F, 0x00}, &v)
+	assert.NotNil(err)
+}
@@ -31,13 +31,18 @@ func NewNamedData(id string, name string, initValue Number,
 		value:     initValue,
 		dataRange: dataRange,
 	}
-	d.warning = NewAlarm(&d, NewRange(0, 0), 0)
-	d.warning.Disable()
-	d.fault = NewAlarm(&d, NewRange(0, 0), 0)
-	d.fault.Disable()
+	d.initAlarms()
 	return d
 }
 
+// initAlarms creates the disabled alarms watching the value of data
+func (data *NamedData) initAlarms() {
+	data.warning = NewAlarm(data, NewRange(0, 0), 0)
+	data.warning.Disable()
+	data.fault = NewAlarm(data, NewRange(0, 0), 0)
+	data.fault.Disable()
+}
+
 func (data *NamedData) Id() string {
 	return data.id
 }
@@ -60,7 +65,7 @@ func (data *NamedData) SetRane(r Range) bool {
 	}
 	origin := data.dataRange
 	data.dataRange.Change(r.low, r.high)
-	data.sigRangeModified.fire(origin, r)
+	data.sigRangeModified.Fire(RangeModifiedArgs{origin, r})
 	oldValue := data.value
 	if data.value < r.low {
 		data.value = r.low
@@ -68,7 +73,7 @@ func (data *NamedData) SetRane(r Range) bool {
 		data.value = r.high
 	}
 	if oldValue != data.value {
-		data.sigModified.fire(data, oldValue, data.value)
+		data.sigModified.Fire(DataModifiedArgs{data, oldValue, data.value})
 	}
 	return true
 }
@@ -90,7 +95,7 @@ func (data *NamedData) SetValue(newValue Number) bool {
 	}
 	tmp := data.value
 	data.value = newValue
-	data.sigModified.fire(data, tmp, newValue)
+	data.sigModified.Fire(DataModifiedArgs{data, tmp, newValue})
 	if data.autoCheck {
 		data.warning.Check()
 		data.fault.Check()
@@ -107,30 +112,42 @@ func (data *NamedData) SetAutoCheck(b bool) {
 }
 
 func (data *NamedData) IsReadable() bool {
-	return data.sigCheckRead.fire(data, data.value)
+	return data.sigCheckRead.Fire(DataCheckArgs{data, data.value})
 }
 
 func (data *NamedData) IsWritable(newValue Number) bool {
-	return data.sigCheckWrite.fire(data, newValue)
+	return data.sigCheckWrite.Fire(DataCheckArgs{data, newValue})
 }
 
 func (data *NamedData) OnModified(
-	f func(*NamedData, Number, Number)) {
-	data.sigModified.Connect(f)
+	f func(*NamedData, Number, Number), opts ...ConnectOption) *Connection {
+	return data.sigModified.Connect(f, opts...)
 }
 
-func (data *NamedData) OnRangeModified(f func(Range, Range)) {
-	data.sigRangeModified.Connect(f)
+func (data *NamedData) OnRangeModified(f func(Range, Range),
+	opts ...ConnectOption) *Connection {
+	return data.sigRangeModified.Connect(f, opts...)
 }
 
 func (data *NamedData) AddCheckReadMethod(
-	f func(*NamedData, Number) bool) {
-	data.sigCheckRead.Connect(f)
+	f func(*NamedData, Number) bool, opts ...ConnectOption) *Connection {
+	return data.sigCheckRead.Connect(f, opts...)
 }
 
 func (data *NamedData) AddCheckWriteMethod(
-	f func(*NamedData, Number) bool) {
-	data.sigCheckWrite.Connect(f)
+	f func(*NamedData, Number) bool, opts ...ConnectOption) *Connection {
+	return data.sigCheckWrite.Connect(f, opts...)
+}
+
+// SignalModified returns the signal of OnModified, such as to make it
+// asynchronous
+func (data *NamedData) SignalModified() *SignalDataModified {
+	return &data.sigModified
+}
+
+// SignalRangeModified returns the signal of OnRangeModified
+func (data *NamedData) SignalRangeModified() *SignalDataRangeModified {
+	return &data.sigRangeModified
 }
 
 func (data *NamedData) SignalCheckRead() SignalDataCheck {
@@ -70,3 +70,17 @@ func TestNameDataAutoCheck(t *testing.T) {
 	assert.EqualValues(d.Warning().AlarmCount(), 0)
 	assert.EqualValues(d.Error().AlarmCount(), 0)
 }
+
+func FuzzNamedDataUnmarshalBinary(f *testing.F) {
+	d := common.NewNamedData("id", "name", 100, common.NewRange(0, 100))
+	data, _ := d.MarshalBinary()
+	f.Add(data)
+	f.Add([]byte{0x00, 0x01, 0xC0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d := common.NewNamedData("id", "name", 0, common.NewRange(0, 100))
+		n, err := d.UnmarshalBinaryWithSize(data)
+		if err == nil && (n < 0 || n > len(data)) {
+			t.Fatalf("consumed %d of %d bytes", n, len(data))
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package common
+
+import (
+	"sync"
+)
+
+// DefaultAsyncQueueSize is the queue size of an AsyncConfig without one
+const DefaultAsyncQueueSize = 64
+
+// OverflowPolicy is what an asynchronous signal does when it fires with a
+// full queue
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the queue
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest queued event
+	OverflowDropOldest
+	// OverflowDropNewest drops the event being fired
+	OverflowDropNewest
+)
+
+// AsyncConfig is how a signal calls its callbacks from a worker goroutine
+type AsyncConfig struct {
+	QueueSize int
+	Overflow  OverflowPolicy
+	// OnPanic is called by the worker with the value recovered from a
+	// callback, the other callbacks are still called
+	OnPanic func(interface{})
+}
+
+// DispatchStats counts the events of an asynchronous signal
+type DispatchStats struct {
+	Queued    int64
+	Delivered int64
+	Dropped   int64
+	Panics    int64
+}
+
+// dispatcher calls the callbacks of a signal from a worker goroutine
+type dispatcher struct {
+	config   AsyncConfig
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	queue    []func()
+	closed   bool
+	stats    DispatchStats
+	done     chan struct{}
+}
+
+func newDispatcher(config AsyncConfig, stats DispatchStats) *dispatcher {
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultAsyncQueueSize
+	}
+	d := &dispatcher{config: config, stats: stats, done: make(chan struct{})}
+	d.notEmpty = sync.NewCond(&d.mu)
+	d.notFull = sync.NewCond(&d.mu)
+	go d.run()
+	return d
+}
+
+// enqueue queues the delivery of an event according to the overflow policy,
+// it returns false if the dispatcher is stopped, the event is then to be
+// delivered by the caller
+func (d *dispatcher) enqueue(job func()) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for !d.closed && len(d.queue) >= d.config.QueueSize {
+		switch d.config.Overflow {
+		case OverflowDropOldest:
+			d.queue[0] = nil
+			d.queue = d.queue[1:]
+			d.stats.Dropped++
+		case OverflowDropNewest:
+			d.stats.Dropped++
+			return true
+		default:
+			d.notFull.Wait()
+		}
+	}
+	if d.closed {
+		// fired while the signal was going back to synchronous
+		return false
+	}
+	d.queue = append(d.queue, job)
+	d.stats.Queued++
+	d.notEmpty.Signal()
+	return true
+}
+
+func (d *dispatcher) run() {
+	defer close(d.done)
+	for {
+		d.mu.Lock()
+		for len(d.queue) == 0 && !d.closed {
+			d.notEmpty.Wait()
+		}
+		if len(d.queue) == 0 {
+			d.mu.Unlock()
+			return
+		}
+		job := d.queue[0]
+		d.queue[0] = nil
+		d.queue = d.queue[1:]
+		d.notFull.Signal()
+		d.mu.Unlock()
+		job()
+		d.mu.Lock()
+		d.stats.Delivered++
+		d.mu.Unlock()
+	}
+}
+
+// call calls f and recovers its panic
+func (d *dispatcher) call(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.mu.Lock()
+			d.stats.Panics++
+			d.mu.Unlock()
+			if d.config.OnPanic != nil {
+				d.config.OnPanic(r)
+			}
+		}
+	}()
+	f()
+}
+
+// stop waits for the queued events to be delivered and stops the worker,
+// blocked enqueue calls return false
+func (d *dispatcher) stop() {
+	d.mu.Lock()
+	d.closed = true
+	d.notEmpty.Broadcast()
+	d.notFull.Broadcast()
+	d.mu.Unlock()
+	<-d.done
+}
+
+func (d *dispatcher) getStats() DispatchStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// setAsync replaces the dispatcher of the signal, nil to call the callbacks
+// synchronously again. The old dispatcher delivers its queued events first.
+func (sig *signal) setAsync(config *AsyncConfig) {
+	l := sig.slots(true)
+	l.mu.Lock()
+	old := l.async
+	l.async = nil
+	l.mu.Unlock()
+	var stats DispatchStats
+	if old != nil {
+		old.stop()
+		stats = old.getStats()
+	} else {
+		stats = l.stats
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if config != nil {
+		l.async = newDispatcher(*config, stats)
+	} else {
+		l.stats = stats
+	}
+}
+
+// dispatch returns the callbacks to call when the signal fires and its
+// dispatcher, nil for a synchronous signal
+func (sig *signal) dispatch() ([]slot, *dispatcher) {
+	l := sig.slots(false)
+	if l == nil {
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.slots, l.async
+}
+
+// SetAsync makes Fire queue the event and return, the callbacks are called
+// in order from a worker goroutine. Events fired while the mode changes are
+// delivered synchronously. The worker runs until StopAsync is called, so a
+// signal which is no longer used must be stopped or its goroutine leaks.
+//
+// The callbacks run concurrently with the code which fired the signal, so
+// they must not dereference a pointer of the event which is still changed,
+// such as the Data of DataModifiedArgs or the Alarm of AlarmArgs fired by a
+// NamedData. SafeNamedData fires its signals with snapshots which can be
+// read safely.
+//
+// A callback must not call StopAsync or SetAsync of its own signal. With
+// OverflowBlock, a callback must not fire its own signal either, the worker
+// would wait forever for room in a full queue.
+func (sig *Signal[T]) SetAsync(config AsyncConfig) {
+	sig.setAsync(&config)
+}
+
+// StopAsync waits for the queued events to be delivered, then makes Fire
+// call the callbacks synchronously again
+func (sig *Signal[T]) StopAsync() {
+	sig.setAsync(nil)
+}
+
+// DispatchStats returns the counters of all the asynchronous modes of sig
+func (sig *Signal[T]) DispatchStats() DispatchStats {
+	l := sig.slots(false)
+	if l == nil {
+		return DispatchStats{}
+	}
+	l.mu.Lock()
+	d, stats := l.async, l.stats
+	l.mu.Unlock()
+	if d != nil {
+		return d.getStats()
+	}
+	return stats
+}
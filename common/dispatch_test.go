@@ -0,0 +1,205 @@
+package common_test
+
+import (
+	"github.com/newkedison/go-utils/common"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingSignal returns an asynchronous signal which first callback waits
+// for release, and the values received in order
+func blockingSignal(config common.AsyncConfig) (sig *common.Signal[int],
+	started chan int, release chan bool, received func() []int) {
+	sig = &common.Signal[int]{}
+	started = make(chan int, 10)
+	release = make(chan bool)
+	var mu sync.Mutex
+	var values []int
+	sig.Connect(func(v int) {
+		started <- v
+		<-release
+		mu.Lock()
+		values = append(values, v)
+		mu.Unlock()
+	})
+	sig.SetAsync(config)
+	received = func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), values...)
+	}
+	return
+}
+
+func TestSignalAsync(t *testing.T) {
+	assert := assert.New(t)
+	sig, started, release, received := blockingSignal(common.AsyncConfig{})
+	for i := 1; i <= 3; i++ {
+		// does not wait for the callback
+		sig.Fire(i)
+	}
+	assert.Equal(<-started, 1)
+	close(release)
+	sig.StopAsync()
+	assert.Equal(received(), []int{1, 2, 3})
+	assert.Equal(sig.DispatchStats(), common.DispatchStats{Queued: 3,
+		Delivered: 3})
+
+	// synchronous again
+	sig.Fire(4)
+	assert.Equal(received(), []int{1, 2, 3, 4})
+}
+
+func TestSignalAsyncDropNewest(t *testing.T) {
+	assert := assert.New(t)
+	sig, started, release, received := blockingSignal(common.AsyncConfig{
+		QueueSize: 1, Overflow: common.OverflowDropNewest})
+	sig.Fire(1)
+	<-started
+	sig.Fire(2)
+	sig.Fire(3)
+	close(release)
+	sig.StopAsync()
+	assert.Equal(received(), []int{1, 2})
+	assert.Equal(sig.DispatchStats(), common.DispatchStats{Queued: 2,
+		Delivered: 2, Dropped: 1})
+}
+
+func TestSignalAsyncDropOldest(t *testing.T) {
+	assert := assert.New(t)
+	sig, started, release, received := blockingSignal(common.AsyncConfig{
+		QueueSize: 1, Overflow: common.OverflowDropOldest})
+	sig.Fire(1)
+	<-started
+	sig.Fire(2)
+	sig.Fire(3)
+	close(release)
+	sig.StopAsync()
+	assert.Equal(received(), []int{1, 3})
+	assert.Equal(sig.DispatchStats(), common.DispatchStats{Queued: 3,
+		Delivered: 2, Dropped: 1})
+}
+
+func TestSignalAsyncBlock(t *testing.T) {
+	assert := assert.New(t)
+	sig, started, release, received := blockingSignal(common.AsyncConfig{
+		QueueSize: 1})
+	sig.Fire(1)
+	<-started
+	sig.Fire(2)
+	fired := make(chan bool)
+	go func() {
+		sig.Fire(3)
+		close(fired)
+	}()
+	select {
+	case <-fired:
+		t.Fatal("Fire did not block on a full queue")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	<-fired
+	sig.StopAsync()
+	assert.Equal(received(), []int{1, 2, 3})
+	assert.EqualValues(sig.DispatchStats().Dropped, 0)
+}
+
+func TestSignalAsyncStopBlocked(t *testing.T) {
+	assert := assert.New(t)
+	sig, started, release, received := blockingSignal(common.AsyncConfig{
+		QueueSize: 1})
+	sig.Fire(1)
+	<-started
+	sig.Fire(2)
+	fired := make(chan bool)
+	go func() {
+		sig.Fire(3)
+		close(fired)
+	}()
+	// wait for Fire to block on the full queue
+	time.Sleep(10 * time.Millisecond)
+	stopped := make(chan bool)
+	go func() {
+		sig.StopAsync()
+		close(stopped)
+	}()
+	// the blocked event is delivered synchronously, not dropped
+	assert.Equal(<-started, 3)
+	close(release)
+	<-fired
+	<-stopped
+	values := received()
+	sort.Ints(values)
+	assert.Equal(values, []int{1, 2, 3})
+	assert.Equal(sig.DispatchStats(), common.DispatchStats{Queued: 2,
+		Delivered: 2})
+}
+
+func TestSignalAsyncPanic(t *testing.T) {
+	assert := assert.New(t)
+	var sig common.Signal[int]
+	var recovered []interface{}
+	var values []int
+	sig.Connect(func(v int) {
+		if v == 2 {
+			panic("bad value")
+		}
+	})
+	sig.Connect(func(v int) { values = append(values, v) })
+	sig.SetAsync(common.AsyncConfig{OnPanic: func(r interface{}) {
+		recovered = append(recovered, r)
+	}})
+	sig.Fire(1)
+	sig.Fire(2)
+	sig.Fire(3)
+	sig.StopAsync()
+	assert.Equal(values, []int{1, 2, 3})
+	assert.Equal(recovered, []interface{}{"bad value"})
+	assert.EqualValues(sig.DispatchStats().Panics, 1)
+
+	// without OnPanic the panic is only counted
+	sig.SetAsync(common.AsyncConfig{})
+	sig.Fire(2)
+	sig.StopAsync()
+	assert.EqualValues(sig.DispatchStats().Panics, 2)
+	assert.EqualValues(sig.DispatchStats().Delivered, 4)
+
+	// a synchronous signal does not recover
+	assert.Panics(func() { sig.Fire(2) })
+}
+
+func TestNamedDataAsyncSignal(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 0, common.NewRange(0, 100))
+	release := make(chan bool)
+	var mu sync.Mutex
+	var values []common.Number
+	d.Update(func(data *common.NamedData) {
+		data.SignalModified().SetAsync(common.AsyncConfig{QueueSize: 2,
+			Overflow: common.OverflowDropOldest})
+	})
+	d.OnModified(func(_ *common.NamedData, _, v common.Number) {
+		<-release
+		mu.Lock()
+		values = append(values, v)
+		mu.Unlock()
+	})
+	// the slow callback does not stall SetValue
+	for i := 1; i <= 10; i++ {
+		assert.True(d.SetValue(common.Number(i)))
+	}
+	close(release)
+	d.Update(func(data *common.NamedData) {
+		data.SignalModified().StopAsync()
+		stats := data.SignalModified().DispatchStats()
+		assert.EqualValues(stats.Queued, 10)
+		assert.EqualValues(stats.Delivered+stats.Dropped, 10)
+		assert.True(stats.Dropped >= 7)
+	})
+	mu.Lock()
+	assert.Equal(values[len(values)-1], common.Number(10))
+	mu.Unlock()
+}
@@ -0,0 +1,494 @@
+package common
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// DefaultTimeUnit is the unit of time.Time and time.Duration values, the
+// same as SAlarmInfo.time
+const DefaultTimeUnit = time.Millisecond
+
+// Encoder marshals values with its own byte order, unlike the package level
+// functions which use the order set by SetByteOrder, so devices with
+// different byte orders can be served at the same time. The zero value uses
+// little-endian and DefaultTimeUnit.
+//
+// Besides numbers, simple types are bool (one byte), time.Time (int64 count
+// of the time unit since the Unix epoch, ErrTimeOverflow is returned when it
+// does not fit, such as for the zero time in nanoseconds), time.Duration
+// (int64 count of the time unit), fixed arrays of simple types, and slices
+// and maps of simple types prefixed by their PackedLength. Map entries are
+// sorted by their encoded keys. The slice fields of Marshal have a u16
+// length prefix instead, so a slice has a different layout as a field of a
+// struct.
+type Encoder struct {
+	order    binary.ByteOrder
+	timeUnit time.Duration
+}
+
+func NewEncoder(order binary.ByteOrder) Encoder {
+	return Encoder{order: order}
+}
+
+func (e Encoder) ByteOrder() binary.ByteOrder {
+	if e.order == nil {
+		return binary.LittleEndian
+	}
+	return e.order
+}
+
+// WithTimeUnit returns a copy of e using unit for time values, unit must
+// divide time.Second
+func (e Encoder) WithTimeUnit(unit time.Duration) Encoder {
+	checkTimeUnit(unit)
+	e.timeUnit = unit
+	return e
+}
+
+func (e Encoder) TimeUnit() time.Duration {
+	if e.timeUnit == 0 {
+		return DefaultTimeUnit
+	}
+	return e.timeUnit
+}
+
+func checkTimeUnit(unit time.Duration) {
+	if unit <= 0 || time.Second%unit != 0 {
+		panic(fmt.Sprintf("invalid time unit %v", unit))
+	}
+}
+
+// timeToUnits returns the count of unit since the Unix epoch, or
+// ErrTimeOverflow if it does not fit in an int64
+func timeToUnits(t time.Time, unit time.Duration) (int64, error) {
+	perSecond := int64(time.Second / unit)
+	seconds := t.Unix()
+	fraction := int64(t.Nanosecond()) / int64(unit)
+	if seconds >= 0 {
+		if seconds > (math.MaxInt64-fraction)/perSecond {
+			return 0, ErrTimeOverflow
+		}
+		return seconds*perSecond + fraction, nil
+	}
+	if fraction > 0 {
+		// a negative fraction, so the product can reach math.MinInt64
+		seconds++
+		fraction -= perSecond
+	}
+	if seconds < (math.MinInt64-fraction)/perSecond {
+		return 0, ErrTimeOverflow
+	}
+	return seconds*perSecond + fraction, nil
+}
+
+func unitsToTime(v int64, unit time.Duration) time.Time {
+	perSecond := int64(time.Second / unit)
+	return time.Unix(v/perSecond, v%perSecond*int64(unit))
+}
+
+var simpleTypes = map[reflect.Type]bool{}
+
+func init() {
+	for _, v := range []interface{}{byte(0), int8(0), int16(0), uint16(0), 0,
+		uint(0), int32(0), uint32(0), int64(0), uint64(0), float32(0),
+		float64(0), false, time.Time{}, time.Duration(0)} {
+		simpleTypes[reflect.TypeOf(v)] = true
+	}
+}
+
+// isSimpleType reports whether values of t are handled by MarshalSimpleType
+func isSimpleType(t reflect.Type) bool {
+	if simpleTypes[t] {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return isSimpleType(t.Elem())
+	case reflect.Map:
+		return isSimpleType(t.Key()) && isSimpleType(t.Elem())
+	}
+	return false
+}
+
+func (e Encoder) MarshalSimpleType(d interface{}) []byte {
+	data, err := e.EncodeSimpleType(d)
+	if err != nil {
+		panic(NewMarshalObjectError(err))
+	}
+	return data
+}
+
+func (e Encoder) EncodeSimpleType(d interface{}) ([]byte, error) {
+	data, err := e.AppendComposite(make([]byte, 0, 8), d)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// grow extends dst by n bytes, the new bytes are returned as the second
+// value so they can be filled by a binary.ByteOrder
+func grow(dst []byte, n int) ([]byte, []byte) {
+	dst = append(dst, make([]byte, n)...)
+	return dst, dst[len(dst)-n:]
+}
+
+// AppendSimpleType is like EncodeSimpleType but appends to dst, it does not
+// allocate if dst has enough capacity. Slices, arrays and maps are left to
+// AppendComposite, walking them by reflection would make d escape, so every
+// call would allocate.
+func (e Encoder) AppendSimpleType(dst []byte, d interface{}) ([]byte, error) {
+	return e.appendScalar(dst, d)
+}
+
+// AppendComposite is like AppendSimpleType but also accepts slices, arrays
+// and maps of simple types
+func (e Encoder) AppendComposite(dst []byte, d interface{}) ([]byte, error) {
+	v := reflect.ValueOf(d)
+	if !v.IsValid() || !isSimpleType(v.Type()) {
+		return dst, errUnknownSimpleType
+	}
+	return e.appendValue(dst, v)
+}
+
+// errUnknownSimpleType is returned for a type which is not a simple type
+var errUnknownSimpleType = fmt.Errorf("MarshalSimpleType: %w", ErrUnknownType)
+
+// appendScalar appends d if it is a number, a bool or a time value, dst is
+// returned unchanged with an error otherwise. It must not let d escape, so
+// the callers of AppendSimpleType do not allocate.
+func (e Encoder) appendScalar(dst []byte, d interface{}) ([]byte, error) {
+	order := e.ByteOrder()
+	var b []byte
+	switch v := d.(type) {
+	case byte:
+		return append(dst, v), nil
+	case int8:
+		return append(dst, byte(v)), nil
+	case bool:
+		if v {
+			return append(dst, 1), nil
+		}
+		return append(dst, 0), nil
+	case int16:
+		dst, b = grow(dst, 2)
+		order.PutUint16(b, uint16(v))
+	case uint16:
+		dst, b = grow(dst, 2)
+		order.PutUint16(b, v)
+	case int:
+		dst, b = grow(dst, 4)
+		order.PutUint32(b, uint32(v))
+	case uint:
+		dst, b = grow(dst, 4)
+		order.PutUint32(b, uint32(v))
+	case int32:
+		dst, b = grow(dst, 4)
+		order.PutUint32(b, uint32(v))
+	case uint32:
+		dst, b = grow(dst, 4)
+		order.PutUint32(b, v)
+	case int64:
+		dst, b = grow(dst, 8)
+		order.PutUint64(b, uint64(v))
+	case uint64:
+		dst, b = grow(dst, 8)
+		order.PutUint64(b, v)
+	case float32:
+		dst, b = grow(dst, 4)
+		order.PutUint32(b, math.Float32bits(v))
+	case float64:
+		dst, b = grow(dst, 8)
+		order.PutUint64(b, math.Float64bits(v))
+	case time.Time:
+		units, err := timeToUnits(v, e.TimeUnit())
+		if err != nil {
+			return dst, err
+		}
+		dst, b = grow(dst, 8)
+		order.PutUint64(b, uint64(units))
+	case time.Duration:
+		dst, b = grow(dst, 8)
+		order.PutUint64(b, uint64(v/e.TimeUnit()))
+	default:
+		return dst, errUnknownSimpleType
+	}
+	return dst, nil
+}
+
+// appendValue appends v, its type has been checked by isSimpleType
+func (e Encoder) appendValue(dst []byte, v reflect.Value) ([]byte, error) {
+	var err error
+	switch v.Kind() {
+	case reflect.Slice:
+		if dst, err = (LengthCodec{}).Append(dst, uint64(v.Len())); err != nil {
+			return dst, err
+		}
+		if v.Type().Elem() == byteType {
+			return append(dst, v.Bytes()...), nil
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len() && err == nil; i++ {
+			dst, err = e.appendValue(dst, v.Index(i))
+		}
+	case reflect.Map:
+		if dst, err = (LengthCodec{}).Append(dst, uint64(v.Len())); err != nil {
+			return dst, err
+		}
+		keys := v.MapKeys()
+		encoded := make([][]byte, len(keys))
+		sorted := make([]int, len(keys))
+		for i, k := range keys {
+			if encoded[i], err = e.appendValue(nil, k); err != nil {
+				return dst, err
+			}
+			sorted[i] = i
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return bytes.Compare(encoded[sorted[i]], encoded[sorted[j]]) < 0
+		})
+		for _, i := range sorted {
+			dst = append(dst, encoded[i]...)
+			if dst, err = e.appendValue(dst, v.MapIndex(keys[i])); err != nil {
+				return dst, err
+			}
+		}
+	default:
+		dst, err = e.appendScalar(dst, v.Interface())
+	}
+	return dst, err
+}
+
+// MarshalString panics if s is longer than 65535 bytes, use a StringCodec
+// for other formats
+func (e Encoder) MarshalString(s string) []byte {
+	data, err := e.EncodeString(s)
+	if err != nil {
+		panic(NewMarshalObjectError(err))
+	}
+	return data
+}
+
+// EncodeString is like MarshalString but returns ErrStringTooLong instead of
+// panic
+func (e Encoder) EncodeString(s string) ([]byte, error) {
+	return e.AppendString(make([]byte, 0, 2+len(s)), s)
+}
+
+// AppendString is like EncodeString but appends to dst
+func (e Encoder) AppendString(dst []byte, s string) ([]byte, error) {
+	return StringCodec{Order: e.ByteOrder()}.Append(dst, s)
+}
+
+// MarshalObject is the same as the package level one, the layout is decided
+// by the object
+func (e Encoder) MarshalObject(obj encoding.BinaryMarshaler) []byte {
+	return MarshalObject(obj)
+}
+
+func (e Encoder) EncodeObject(obj encoding.BinaryMarshaler) ([]byte, error) {
+	return EncodeObject(obj)
+}
+
+func (e Encoder) AppendObject(dst []byte,
+	obj encoding.BinaryMarshaler) ([]byte, error) {
+	return AppendObject(dst, obj)
+}
+
+// Marshal encodes v as described by the `bin` tags of its fields, fields
+// without be/le tag use the order of e
+func (e Encoder) Marshal(v interface{}) ([]byte, error) {
+	return marshal(v, e.ByteOrder(), e.TimeUnit())
+}
+
+// Decoder is the counterpart of Encoder
+type Decoder struct {
+	order    binary.ByteOrder
+	timeUnit time.Duration
+}
+
+func NewDecoder(order binary.ByteOrder) Decoder {
+	return Decoder{order: order}
+}
+
+func (d Decoder) ByteOrder() binary.ByteOrder {
+	if d.order == nil {
+		return binary.LittleEndian
+	}
+	return d.order
+}
+
+// WithTimeUnit returns a copy of d using unit for time values, see
+// Encoder.WithTimeUnit
+func (d Decoder) WithTimeUnit(unit time.Duration) Decoder {
+	checkTimeUnit(unit)
+	d.timeUnit = unit
+	return d
+}
+
+func (d Decoder) TimeUnit() time.Duration {
+	if d.timeUnit == 0 {
+		return DefaultTimeUnit
+	}
+	return d.timeUnit
+}
+
+func (d Decoder) UnmarshalSimpleType(p interface{}, data []byte) int {
+	n, err := d.DecodeSimpleType(p, data)
+	if err != nil {
+		panic(NewUnmarshalObjectError(err))
+	}
+	return n
+}
+
+func (d Decoder) DecodeSimpleType(p interface{}, data []byte) (int, error) {
+	size := simpleTypeSize(p)
+	if size == 0 {
+		rv := reflect.ValueOf(p)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() ||
+			!isSimpleType(rv.Type().Elem()) {
+			return 0, fmt.Errorf("UnmarshalSimpleType: %w", ErrUnknownType)
+		}
+		return d.decodeComposite(rv.Elem(), data)
+	}
+	if err := CheckBuffer(data, size, 0); err != nil {
+		return 0, err
+	}
+	order := d.ByteOrder()
+	switch v := p.(type) {
+	case *byte:
+		*v = data[0]
+	case *int8:
+		*v = int8(data[0])
+	case *int16:
+		*v = int16(order.Uint16(data))
+	case *uint16:
+		*v = order.Uint16(data)
+	case *int:
+		*v = int(int32(order.Uint32(data)))
+	case *uint:
+		*v = uint(order.Uint32(data))
+	case *int32:
+		*v = int32(order.Uint32(data))
+	case *uint32:
+		*v = order.Uint32(data)
+	case *int64:
+		*v = int64(order.Uint64(data))
+	case *uint64:
+		*v = order.Uint64(data)
+	case *float32:
+		*v = math.Float32frombits(order.Uint32(data))
+	case *float64:
+		*v = math.Float64frombits(order.Uint64(data))
+	case *bool:
+		*v = data[0] != 0
+	case *time.Time:
+		*v = unitsToTime(int64(order.Uint64(data)), d.TimeUnit())
+	case *time.Duration:
+		*v = time.Duration(order.Uint64(data)) * d.TimeUnit()
+	}
+	return size, nil
+}
+
+// decodeComposite decodes a slice, an array or a map of simple types
+func (d Decoder) decodeComposite(v reflect.Value, data []byte) (int, error) {
+	if v.Kind() == reflect.Array {
+		return d.decodeElements(v, data, 0)
+	}
+	length, n, err := LengthCodec{}.Unpack(data)
+	if err != nil {
+		return 0, err
+	}
+	// each element takes at least one byte, reject huge lengths before
+	// allocating
+	if length > uint64(len(data)-n) {
+		return 0, shortBufferOf(length, data, n)
+	}
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), int(length), int(length)))
+		return d.decodeElements(v, data, n)
+	}
+	m := reflect.MakeMapWithSize(v.Type(), int(length))
+	for i := 0; i < int(length); i++ {
+		key := reflect.New(v.Type().Key())
+		value := reflect.New(v.Type().Elem())
+		for _, p := range []reflect.Value{key, value} {
+			k, err := d.DecodeSimpleType(p.Interface(), data[n:])
+			if err != nil {
+				return 0, shiftShortBuffer(err, data, n)
+			}
+			n += k
+		}
+		m.SetMapIndex(key.Elem(), value.Elem())
+	}
+	v.Set(m)
+	return n, nil
+}
+
+// decodeElements decodes the elements of v from data[offset:], it returns
+// the offset after them
+func (d Decoder) decodeElements(v reflect.Value, data []byte,
+	offset int) (int, error) {
+	if v.Kind() == reflect.Slice && v.Type().Elem() == byteType {
+		if err := CheckBuffer(data, v.Len(), offset); err != nil {
+			return 0, err
+		}
+		return offset + copy(v.Bytes(), data[offset:]), nil
+	}
+	for i := 0; i < v.Len(); i++ {
+		k, err := d.DecodeSimpleType(v.Index(i).Addr().Interface(),
+			data[offset:])
+		if err != nil {
+			return 0, shiftShortBuffer(err, data, offset)
+		}
+		offset += k
+	}
+	return offset, nil
+}
+
+// shiftShortBuffer makes a *ShortBufferError of data[offset:] relative to
+// data
+func shiftShortBuffer(err error, data []byte, offset int) error {
+	var short *ShortBufferError
+	if errors.As(err, &short) {
+		return &ShortBufferError{short.Required, len(data),
+			offset + short.Offset}
+	}
+	return err
+}
+
+func (d Decoder) UnmarshalString(dest *string, data []byte) int {
+	n, err := d.DecodeString(dest, data)
+	if err != nil {
+		panic(NewUnmarshalObjectError(err))
+	}
+	return n
+}
+
+func (d Decoder) DecodeString(dest *string, data []byte) (int, error) {
+	return StringCodec{Order: d.ByteOrder()}.Decode(dest, data)
+}
+
+func (d Decoder) UnmarshalObject(dest BinaryUnmarshalerWithSize,
+	data []byte) int {
+	return UnmarshalObject(dest, data)
+}
+
+func (d Decoder) DecodeObject(dest BinaryUnmarshalerWithSize,
+	data []byte) (int, error) {
+	return DecodeObject(dest, data)
+}
+
+// Unmarshal decodes data into v, see Encoder.Marshal
+func (d Decoder) Unmarshal(data []byte, v interface{}) (int, error) {
+	return unmarshal(data, v, d.ByteOrder(), d.TimeUnit())
+}
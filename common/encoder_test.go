@@ -0,0 +1,200 @@
+package common_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/newkedison/go-utils/common"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEncoderSimpleType(t *testing.T) {
+	assert := assert.New(t)
+	be := common.NewEncoder(binary.BigEndian)
+	assert.Equal(be.ByteOrder(), binary.BigEndian)
+	assert.Equal(common.Encoder{}.ByteOrder(), binary.LittleEndian)
+	assert.Equal(be.MarshalSimpleType(uint16(0x1234)), []byte{0x12, 0x34})
+	assert.Equal(be.MarshalSimpleType(-2), []byte{0xFF, 0xFF, 0xFF, 0xFE})
+	assert.Equal(be.MarshalSimpleType(float32(1.5)),
+		[]byte{0x3F, 0xC0, 0x00, 0x00})
+	cdab := common.NewEncoder(common.BigEndianWordSwap)
+	assert.Equal(cdab.MarshalSimpleType(float32(1.5)),
+		[]byte{0x00, 0x00, 0x3F, 0xC0})
+	assert.Equal(common.Encoder{}.MarshalSimpleType(uint16(0x1234)),
+		[]byte{0x34, 0x12})
+	_, err := be.EncodeSimpleType("a")
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	assert.Panics(func() { be.MarshalSimpleType("a") })
+
+	d := common.NewDecoder(common.BigEndianWordSwap)
+	assert.Equal(d.ByteOrder(), common.BigEndianWordSwap)
+	assert.Equal(common.Decoder{}.ByteOrder(), binary.LittleEndian)
+	var f float32
+	assert.Equal(d.UnmarshalSimpleType(&f, []byte{0x00, 0x00, 0x3F, 0xC0}), 4)
+	assert.EqualValues(f, 1.5)
+	var i64 int64
+	n, err := common.NewDecoder(binary.BigEndian).DecodeSimpleType(&i64,
+		[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFE})
+	assert.Nil(err)
+	assert.Equal(n, 8)
+	assert.EqualValues(i64, -2)
+	_, err = d.DecodeSimpleType(&i64, nil)
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+	_, err = d.DecodeSimpleType(i64, nil)
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	assert.Panics(func() { d.UnmarshalSimpleType(&i64, nil) })
+}
+
+func TestEncoderString(t *testing.T) {
+	assert := assert.New(t)
+	be := common.NewEncoder(binary.BigEndian)
+	assert.Equal(be.MarshalString("AB"), []byte{0x00, 0x02, 0x41, 0x42})
+	_, err := be.EncodeString(string(make([]byte, math.MaxUint16+1)))
+	assert.Equal(err, common.ErrStringTooLong)
+	d := common.NewDecoder(binary.BigEndian)
+	var s string
+	assert.Equal(d.UnmarshalString(&s, []byte{0x00, 0x02, 0x41, 0x42}), 4)
+	assert.Equal(s, "AB")
+	_, err = d.DecodeString(&s, []byte{0x02, 0x00, 0x41, 0x42})
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+	assert.Panics(func() { d.UnmarshalString(&s, nil) })
+}
+
+func TestEncoderObject(t *testing.T) {
+	assert := assert.New(t)
+	e := common.NewEncoder(binary.BigEndian)
+	assert.Equal(e.MarshalObject(marshalableObject(1)),
+		common.MarshalObject(marshalableObject(1)))
+	_, err := e.EncodeObject(marshalableObject(0))
+	assert.NotNil(err)
+	d := common.NewDecoder(binary.BigEndian)
+	var o marshalableObject
+	assert.Equal(d.UnmarshalObject(&o, []byte{0x0A, 0x00, 0x00, 0x00}), 4)
+	_, err = d.DecodeObject(&o, []byte{0x00, 0x00, 0x00, 0x00})
+	assert.NotNil(err)
+}
+
+func TestEncoderMarshal(t *testing.T) {
+	assert := assert.New(t)
+	type register struct {
+		Value float32
+		Count uint16 `bin:"le"`
+	}
+	e := common.NewEncoder(common.BigEndianWordSwap)
+	buf, err := e.Marshal(register{1.5, 0x0102})
+	assert.Nil(err)
+	assert.Equal(buf, []byte{0x00, 0x00, 0x3F, 0xC0, 0x02, 0x01})
+	var r register
+	n, err := common.NewDecoder(common.BigEndianWordSwap).Unmarshal(buf, &r)
+	assert.Nil(err)
+	assert.Equal(n, 6)
+	assert.Equal(r, register{1.5, 0x0102})
+}
+
+func TestEncoderSliceLayout(t *testing.T) {
+	assert := assert.New(t)
+	e := common.NewEncoder(binary.LittleEndian)
+	values := []uint16{1, 2}
+	// a slice field has a u16 length prefix
+	buf, err := e.Marshal(struct{ A []uint16 }{values})
+	assert.Nil(err)
+	assert.Equal(buf, []byte{0x02, 0x00, 0x01, 0x00, 0x02, 0x00})
+	// a simple type slice has a PackedLength prefix
+	assert.Equal(e.MarshalSimpleType(values),
+		[]byte{0x02, 0x01, 0x00, 0x02, 0x00})
+	buf, err = e.Marshal(struct{ A []uint16 }{make([]uint16, 200)})
+	assert.Nil(err)
+	assert.Equal(buf[:2], []byte{0xC8, 0x00})
+	assert.Equal(e.MarshalSimpleType(make([]uint16, 200))[:2],
+		[]byte{0x80, 0xC8})
+}
+
+func TestEncoderAppend(t *testing.T) {
+	assert := assert.New(t)
+	e := common.NewEncoder(common.BigEndianWordSwap)
+	dst, err := e.AppendSimpleType([]byte{0xAA}, float32(1.5))
+	assert.Nil(err)
+	assert.Equal(dst, []byte{0xAA, 0x00, 0x00, 0x3F, 0xC0})
+	dst, err = common.NewEncoder(binary.BigEndian).AppendString(dst[:1], "AB")
+	assert.Nil(err)
+	assert.Equal(dst, []byte{0xAA, 0x00, 0x02, 0x41, 0x42})
+	assert.Equal(common.NewEncoder(binary.BigEndian).MarshalString("AB"),
+		dst[1:])
+}
+
+func TestEncoderTimeUnit(t *testing.T) {
+	assert := assert.New(t)
+	tm := time.Unix(-2, 5)
+	e := common.NewEncoder(binary.BigEndian).WithTimeUnit(time.Nanosecond)
+	assert.Equal(e.TimeUnit(), time.Nanosecond)
+	assert.Equal(common.Encoder{}.TimeUnit(), common.DefaultTimeUnit)
+	assert.Equal(e.MarshalSimpleType(tm), e.MarshalSimpleType(tm.UnixNano()))
+	assert.Equal(e.MarshalSimpleType(time.Second),
+		e.MarshalSimpleType(int64(time.Second)))
+	d := common.NewDecoder(binary.BigEndian).WithTimeUnit(time.Nanosecond)
+	var result time.Time
+	d.UnmarshalSimpleType(&result, e.MarshalSimpleType(tm))
+	assert.True(result.Equal(tm))
+
+	e = common.Encoder{}.WithTimeUnit(time.Second)
+	assert.Equal(e.MarshalSimpleType(time.Unix(-2, 5)),
+		e.MarshalSimpleType(int64(-2)))
+	d = common.Decoder{}.WithTimeUnit(time.Second)
+	d.UnmarshalSimpleType(&result, e.MarshalSimpleType(int64(-3)))
+	assert.True(result.Equal(time.Unix(-3, 0)))
+	assert.Panics(func() { e.WithTimeUnit(time.Minute) })
+	assert.Panics(func() { d.WithTimeUnit(7 * time.Nanosecond) })
+	assert.Panics(func() { d.WithTimeUnit(0) })
+}
+
+func TestEncoderTimeOverflow(t *testing.T) {
+	assert := assert.New(t)
+	e := common.NewEncoder(binary.BigEndian).WithTimeUnit(time.Nanosecond)
+	_, err := e.EncodeSimpleType(time.Time{})
+	assert.Equal(err, common.ErrTimeOverflow)
+	_, err = e.AppendSimpleType(nil, time.Unix(0, math.MaxInt64).Add(1))
+	assert.Equal(err, common.ErrTimeOverflow)
+	_, err = e.AppendComposite(nil, []time.Time{time.Unix(0, math.MinInt64),
+		time.Unix(0, math.MinInt64).Add(-1)})
+	assert.Equal(err, common.ErrTimeOverflow)
+	_, err = e.Marshal(struct{ T time.Time }{})
+	assert.True(errors.Is(err, common.ErrTimeOverflow))
+	// the limits are encoded
+	buf, err := e.EncodeSimpleType(time.Unix(0, math.MaxInt64))
+	assert.Nil(err)
+	assert.Equal(buf, e.MarshalSimpleType(int64(math.MaxInt64)))
+	buf, err = e.EncodeSimpleType(time.Unix(0, math.MinInt64))
+	assert.Nil(err)
+	assert.Equal(buf, e.MarshalSimpleType(int64(math.MinInt64)))
+	// the zero time fits in milliseconds
+	_, err = common.Encoder{}.EncodeSimpleType(time.Time{})
+	assert.Nil(err)
+}
+
+func TestEncoderMarshalTimeUnit(t *testing.T) {
+	assert := assert.New(t)
+	type record struct {
+		Time    time.Time
+		Timeout time.Duration `bin:"u32"`
+	}
+	v := record{time.Unix(1, 5), 70 * time.Microsecond}
+	e := common.NewEncoder(binary.BigEndian).WithTimeUnit(time.Nanosecond)
+	buf, err := e.Marshal(v)
+	assert.Nil(err)
+	assert.Equal(buf, append(e.MarshalSimpleType(v.Time),
+		0x00, 0x01, 0x11, 0x70))
+	d := common.NewDecoder(binary.BigEndian).WithTimeUnit(time.Nanosecond)
+	var result record
+	n, err := d.Unmarshal(buf, &result)
+	assert.Nil(err)
+	assert.Equal(n, 12)
+	assert.True(result.Time.Equal(v.Time))
+	assert.Equal(result.Timeout, v.Timeout)
+
+	// a Decoder with the default unit reads the counts as milliseconds
+	_, err = common.NewDecoder(binary.BigEndian).Unmarshal(buf, &result)
+	assert.Nil(err)
+	assert.Equal(result.Timeout, 70000*time.Millisecond)
+}
@@ -0,0 +1,260 @@
+package common
+
+import (
+	"errors"
+	proto "github.com/golang/protobuf/proto"
+	"io"
+	"math"
+)
+
+// DefaultMaxFrameSize is the default limit of the payload read by
+// FrameReader. A corrupted length up to the limit holds back the frames
+// after it until that many bytes are read, so it is kept small.
+const DefaultMaxFrameSize = 64 << 10
+
+// frameReadSize is the minimum free space of the buffer before a Read
+const frameReadSize = 4096
+
+// ErrFrameTooLarge is returned by FrameWriter for payloads over its limit
+var ErrFrameTooLarge = errors.New("Frame too large")
+
+// FrameStats counts what happened to the bytes of a stream of frames
+type FrameStats struct {
+	Frames         int64
+	DiscardedBytes int64
+	CrcErrors      int64
+}
+
+// appendFrame appends the frame of MarshalProtoMessage holding payload
+func appendFrame(dst []byte, payload []byte) []byte {
+	start := len(dst)
+	dst, _ = LengthCodec{}.Append(dst, uint64(len(payload)))
+	dst = append(dst, payload...)
+	return appendFrameCrc(dst, start)
+}
+
+// appendFrameCrc appends the CRC of the frame starting at dst[start]
+func appendFrameCrc(dst []byte, start int) []byte {
+	frame := protoMessageCrc.Append(dst[start:])
+	// frame shares the memory of dst unless Append had to grow it
+	return append(dst[:start], frame...)
+}
+
+// frameHeader decodes the PackedLength at the start of data and returns its
+// size, 0 if data can not start a PackedLength. complete is false if data
+// only holds the first bytes of it, length is then 0.
+func frameHeader(data []byte) (length uint32, size int, complete bool) {
+	length, size, err := UnpackLength(ByteArray(data))
+	if err == nil {
+		return length, size, true
+	}
+	max := ByteCountOfPackedLength(math.MaxUint32)
+	if len(data) >= max {
+		return 0, 0, false
+	}
+	// the missing bytes are taken as 0xFF, which is a canonical length for
+	// every prefix, so only a truncated valid PackedLength is decoded
+	var padded [8]byte
+	for i := copy(padded[:max], data); i < max; i++ {
+		padded[i] = 0xFF
+	}
+	_, size, err = UnpackLength(ByteArray(padded[:max]))
+	if err != nil || size <= len(data) {
+		return 0, 0, false
+	}
+	return 0, size, false
+}
+
+// nextFrame skips the garbage at the start of data and returns the number
+// of bytes skipped and the size of the valid frame after them. The size is 0
+// if the frame is not complete, need is then the number of bytes required
+// after the skipped ones. Skipped bytes and CRC errors are added to stats.
+func nextFrame(data []byte, maxSize int,
+	stats *FrameStats) (skipped, size, need int) {
+	for skipped < len(data) {
+		rest := data[skipped:]
+		length, headerSize, complete := frameHeader(rest)
+		if headerSize > 0 && !complete {
+			return skipped, 0, headerSize
+		}
+		if headerSize == 0 || uint64(length) > uint64(maxSize) {
+			skipped++
+			stats.DiscardedBytes++
+			continue
+		}
+		total := headerSize + int(length) + protoMessageCrc.Size()
+		if len(rest) < total {
+			return skipped, 0, total
+		}
+		if !protoMessageCrc.Verify(rest[:total]) {
+			skipped++
+			stats.DiscardedBytes++
+			stats.CrcErrors++
+			continue
+		}
+		return skipped, total, total
+	}
+	return skipped, 0, 1
+}
+
+// framePayload returns the payload of a valid frame
+func framePayload(frame []byte) []byte {
+	_, start, _ := UnpackLength(ByteArray(frame))
+	return frame[start : len(frame)-protoMessageCrc.Size()]
+}
+
+// frameLimit returns the limit of the payload set by SetMaxFrameSize, a
+// negative n is taken as 0 so only empty payloads are allowed
+func frameLimit(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// FrameWriter writes the frames of MarshalProtoMessage to an io.Writer, each
+// frame is written by a single Write call
+type FrameWriter struct {
+	w       io.Writer
+	maxSize int
+}
+
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	return &FrameWriter{w: w, maxSize: DefaultMaxFrameSize}
+}
+
+// SetMaxFrameSize sets the limit of the payload, so the peer with the same
+// limit can read all frames written. A negative n is taken as 0.
+func (fw *FrameWriter) SetMaxFrameSize(n int) {
+	fw.maxSize = frameLimit(n)
+}
+
+// WriteFrame writes payload as a frame
+func (fw *FrameWriter) WriteFrame(payload []byte) error {
+	if len(payload) > fw.maxSize {
+		return ErrFrameTooLarge
+	}
+	_, err := fw.w.Write(appendFrame(nil, payload))
+	return err
+}
+
+func (fw *FrameWriter) WriteMessage(pb proto.Message) error {
+	data, err := proto.Marshal(pb)
+	if err != nil {
+		return err
+	}
+	return fw.WriteFrame(data)
+}
+
+// FrameReader reads the frames of MarshalProtoMessage from an io.Reader. The
+// stream is resynchronised byte by byte after an invalid length, a length
+// over the limit or a CRC error, the skipped bytes are counted in Stats.
+type FrameReader struct {
+	r io.Reader
+	// buf[start:] are the bytes read but not consumed
+	buf     []byte
+	start   int
+	err     error
+	maxSize int
+	stats   FrameStats
+}
+
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: r, maxSize: DefaultMaxFrameSize}
+}
+
+// SetMaxFrameSize sets the limit of the payload, a length over it is taken as
+// garbage, so a corrupted length can not make the reader allocate or wait
+// for a huge frame. A negative n is taken as 0.
+func (fr *FrameReader) SetMaxFrameSize(n int) {
+	fr.maxSize = frameLimit(n)
+}
+
+func (fr *FrameReader) Stats() FrameStats {
+	return fr.stats
+}
+
+func (fr *FrameReader) buffered() []byte {
+	return fr.buf[fr.start:]
+}
+
+// fill reads until n bytes are buffered, it returns false on read error
+func (fr *FrameReader) fill(n int) bool {
+	for empty := 0; len(fr.buffered()) < n; {
+		if fr.err != nil {
+			return false
+		}
+		if fr.start > 0 {
+			fr.buf = fr.buf[:copy(fr.buf, fr.buffered())]
+			fr.start = 0
+		}
+		if free := cap(fr.buf) - len(fr.buf); free < frameReadSize ||
+			free < n-len(fr.buf) {
+			size := 2*cap(fr.buf) + frameReadSize
+			if size < n {
+				size = n
+			}
+			buf := make([]byte, len(fr.buf), size)
+			copy(buf, fr.buf)
+			fr.buf = buf
+		}
+		k, err := fr.r.Read(fr.buf[len(fr.buf):cap(fr.buf)])
+		fr.buf = fr.buf[:len(fr.buf)+k]
+		if err != nil {
+			fr.err = err
+		} else if k == 0 {
+			// same limit as bufio.Reader
+			if empty++; empty >= 100 {
+				fr.err = io.ErrNoProgress
+			}
+		}
+	}
+	return true
+}
+
+func (fr *FrameReader) discard(n int) {
+	fr.start += n
+	fr.stats.DiscardedBytes += int64(n)
+}
+
+// ReadFrame returns the payload of the next valid frame. It returns io.EOF
+// at the end of the stream, io.ErrUnexpectedEOF if the stream ends with an
+// incomplete frame, or the error of the underlying reader.
+func (fr *FrameReader) ReadFrame() ([]byte, error) {
+	truncated := false
+	for {
+		skipped, size, need := nextFrame(fr.buffered(), fr.maxSize, &fr.stats)
+		fr.start += skipped
+		if size > 0 {
+			frame := fr.buffered()[:size]
+			fr.start += size
+			fr.stats.Frames++
+			payload := framePayload(frame)
+			return append(make([]byte, 0, len(payload)), payload...), nil
+		}
+		if fr.fill(need) {
+			continue
+		}
+		if fr.err != io.EOF {
+			return nil, fr.err
+		}
+		if len(fr.buffered()) == 0 {
+			if truncated {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, io.EOF
+		}
+		// the incomplete frame may be garbage before a complete one
+		fr.discard(1)
+		truncated = true
+	}
+}
+
+// ReadMessage reads the next valid frame into out
+func (fr *FrameReader) ReadMessage(out proto.Message) error {
+	payload, err := fr.ReadFrame()
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(payload, out)
+}
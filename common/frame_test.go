@@ -0,0 +1,175 @@
+package common_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/newkedison/go-utils/common"
+	"github.com/newkedison/go-utils/internal/types"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func makeFrame(payload []byte) []byte {
+	var buf bytes.Buffer
+	common.NewFrameWriter(&buf).WriteFrame(payload)
+	return buf.Bytes()
+}
+
+func TestFrameWriter(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	w := common.NewFrameWriter(&buf)
+	pb := common.NewNumber(1).ToProtoMessage()
+	assert.Nil(w.WriteMessage(pb))
+	expected, _ := common.MarshalProtoMessage(pb)
+	assert.Equal(buf.Bytes(), expected)
+	assert.NotNil(w.WriteMessage(nil))
+
+	buf.Reset()
+	assert.Nil(w.WriteFrame(make([]byte, 300)))
+	assert.Equal(buf.Len(), 3+300+2)
+	w.SetMaxFrameSize(299)
+	assert.Equal(w.WriteFrame(make([]byte, 300)), common.ErrFrameTooLarge)
+}
+
+func TestFrameReader(t *testing.T) {
+	assert := assert.New(t)
+	var stream []byte
+	stream = append(stream, 0xFF, 0x81) // invalid header
+	stream = append(stream, makeFrame([]byte("first"))...)
+	bad := makeFrame([]byte("bad"))
+	bad[2] ^= 0x01
+	stream = append(stream, bad...)
+	stream = append(stream, makeFrame(make([]byte, 5000))...)
+	stream = append(stream, makeFrame(nil)...)
+	pb, _ := common.MarshalProtoMessage(common.NewNumber(2).ToProtoMessage())
+	stream = append(stream, pb...)
+
+	r := common.NewFrameReader(iotest.OneByteReader(bytes.NewReader(stream)))
+	payload, err := r.ReadFrame()
+	assert.Nil(err)
+	assert.Equal(payload, []byte("first"))
+	payload, err = r.ReadFrame()
+	assert.Nil(err)
+	assert.Equal(payload, make([]byte, 5000))
+	payload, err = r.ReadFrame()
+	assert.Nil(err)
+	assert.Equal(payload, []byte{})
+	var n types.WSNumber
+	assert.Nil(r.ReadMessage(&n))
+	assert.EqualValues(n.Value, 2)
+	_, err = r.ReadFrame()
+	assert.Equal(err, io.EOF)
+	assert.Equal(r.ReadMessage(&n), io.EOF)
+	stats := r.Stats()
+	assert.EqualValues(stats.Frames, 4)
+	assert.EqualValues(stats.DiscardedBytes, 2+len(bad))
+	assert.True(stats.CrcErrors >= 1)
+}
+
+func TestFrameReaderMaxSize(t *testing.T) {
+	assert := assert.New(t)
+	stream := append(makeFrame(make([]byte, 300)), makeFrame([]byte("ok"))...)
+	r := common.NewFrameReader(bytes.NewReader(stream))
+	r.SetMaxFrameSize(299)
+	payload, err := r.ReadFrame()
+	assert.Nil(err)
+	assert.Equal(payload, []byte("ok"))
+	assert.EqualValues(r.Stats().DiscardedBytes, 3+300+2)
+}
+
+func TestFrameNegativeMaxSize(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	w := common.NewFrameWriter(&buf)
+	w.SetMaxFrameSize(-1)
+	assert.Equal(w.WriteFrame([]byte("a")), common.ErrFrameTooLarge)
+	assert.Nil(w.WriteFrame(nil))
+
+	stream := append(makeFrame([]byte("abc")), makeFrame(nil)...)
+	r := common.NewFrameReader(bytes.NewReader(stream))
+	r.SetMaxFrameSize(-1)
+	payload, err := r.ReadFrame()
+	assert.Nil(err)
+	assert.Equal(payload, []byte{})
+	assert.EqualValues(r.Stats().DiscardedBytes, len(makeFrame([]byte("abc"))))
+}
+
+func TestFrameReaderTruncated(t *testing.T) {
+	assert := assert.New(t)
+	frame := makeFrame([]byte("abc"))
+	// a header declaring more data than the stream holds, before a frame
+	stream := append([]byte{0x10}, frame...)
+	r := common.NewFrameReader(bytes.NewReader(stream))
+	payload, err := r.ReadFrame()
+	assert.Nil(err)
+	assert.Equal(payload, []byte("abc"))
+	_, err = r.ReadFrame()
+	assert.Equal(err, io.EOF)
+	assert.EqualValues(r.Stats().DiscardedBytes, 1)
+
+	r = common.NewFrameReader(bytes.NewReader(frame[:len(frame)-1]))
+	_, err = r.ReadFrame()
+	assert.Equal(err, io.ErrUnexpectedEOF)
+	assert.EqualValues(r.Stats().DiscardedBytes, len(frame)-1)
+	_, err = r.ReadFrame()
+	assert.Equal(err, io.EOF)
+}
+
+func TestFrameReaderCorruptLength(t *testing.T) {
+	assert := assert.New(t)
+	frame := makeFrame([]byte("ok"))
+	// a header of 192KB over the default limit does not wait for more data
+	stream := append([]byte{0xE0, 0x03, 0x00, 0x00}, frame...)
+	r := common.NewFrameReader(io.MultiReader(
+		bytes.NewReader(stream), errorReader{}))
+	payload, err := r.ReadFrame()
+	assert.Nil(err)
+	assert.Equal(payload, []byte("ok"))
+	assert.EqualValues(r.Stats().DiscardedBytes, 4)
+
+	// the start of a valid header waits for the rest, garbage does not
+	r = common.NewFrameReader(io.MultiReader(
+		bytes.NewReader([]byte{0xE0, 0x01}), errorReader{}))
+	_, err = r.ReadFrame()
+	assert.EqualError(err, "read error")
+	assert.EqualValues(r.Stats().DiscardedBytes, 0)
+	r = common.NewFrameReader(io.MultiReader(
+		bytes.NewReader([]byte{0xE0, 0x00}), errorReader{}))
+	_, err = r.ReadFrame()
+	assert.EqualError(err, "read error")
+	// 0xE0 0x00 can only start a non-canonical length, 0x00 is a header
+	assert.EqualValues(r.Stats().DiscardedBytes, 1)
+}
+
+type errorReader struct{}
+
+func (errorReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read error")
+}
+
+type emptyReader struct{}
+
+func (emptyReader) Read(p []byte) (int, error) {
+	return 0, nil
+}
+
+func TestFrameReaderError(t *testing.T) {
+	assert := assert.New(t)
+	frame := makeFrame([]byte("abc"))
+	r := common.NewFrameReader(io.MultiReader(
+		bytes.NewReader(frame), bytes.NewReader(frame[:2]), errorReader{}))
+	payload, err := r.ReadFrame()
+	assert.Nil(err)
+	assert.Equal(payload, []byte("abc"))
+	_, err = r.ReadFrame()
+	assert.EqualError(err, "read error")
+	_, err = r.ReadFrame()
+	assert.EqualError(err, "read error")
+
+	r = common.NewFrameReader(emptyReader{})
+	_, err = r.ReadFrame()
+	assert.Equal(err, io.ErrNoProgress)
+}
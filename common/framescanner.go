@@ -0,0 +1,104 @@
+package common
+
+import (
+	"bufio"
+)
+
+// FrameScanner extracts the frames of MarshalProtoMessage from a stream of
+// bytes arriving in arbitrary chunks. Corrupt bytes before a frame are
+// skipped one by one until a frame with a valid length and CRC is found, the
+// skipped bytes and CRC errors are counted in Stats.
+//
+// Split can be given to bufio.Scanner, the tokens are whole frames which can
+// be decoded by UnmarshalProtoMessage. Split takes a length which would make
+// a token over the limit of the scanner as garbage, so bufio.Scanner never
+// stops with bufio.ErrTooLong, see SetMaxTokenSize. Write and Next do the same
+// on an internal buffer.
+type FrameScanner struct {
+	maxSize   int
+	tokenSize int
+	stats     FrameStats
+	// buf[start:] are the bytes written but not consumed by Next
+	buf   []byte
+	start int
+}
+
+func NewFrameScanner() *FrameScanner {
+	return &FrameScanner{maxSize: DefaultMaxFrameSize,
+		tokenSize: bufio.MaxScanTokenSize}
+}
+
+// SetMaxFrameSize sets the limit of the payload, see FrameReader
+func (s *FrameScanner) SetMaxFrameSize(n int) {
+	s.maxSize = frameLimit(n)
+}
+
+// SetMaxTokenSize sets the max given to bufio.Scanner.Buffer, so Split can
+// return larger frames. The default is bufio.MaxScanTokenSize.
+func (s *FrameScanner) SetMaxTokenSize(n int) {
+	s.tokenSize = n
+}
+
+func (s *FrameScanner) Stats() FrameStats {
+	return s.stats
+}
+
+// Split implements bufio.SplitFunc
+func (s *FrameScanner) Split(data []byte, atEOF bool) (int, []byte, error) {
+	// the largest payload of a frame which fits in a token
+	maxSize := s.tokenSize - ByteCountOfPackedLength(uint32(s.tokenSize)) -
+		protoMessageCrc.Size()
+	if maxSize > s.maxSize {
+		maxSize = s.maxSize
+	}
+	if maxSize < 0 {
+		// a buffer too small for a frame header, only empty frames fit
+		maxSize = 0
+	}
+	return s.split(data, atEOF, maxSize)
+}
+
+func (s *FrameScanner) split(data []byte, atEOF bool,
+	maxSize int) (int, []byte, error) {
+	advance := 0
+	for {
+		skipped, size, _ := nextFrame(data[advance:], maxSize, &s.stats)
+		advance += skipped
+		if size > 0 {
+			s.stats.Frames++
+			return advance + size, data[advance : advance+size], nil
+		}
+		if !atEOF || advance == len(data) {
+			return advance, nil, nil
+		}
+		// the incomplete frame at the end may be garbage before a complete
+		// one, bufio.Scanner stops after a call without token at EOF, so the
+		// whole rest is handled here
+		advance++
+		s.stats.DiscardedBytes++
+	}
+}
+
+// Write appends a chunk of the stream, it never fails so a FrameScanner can
+// be the destination of io.Copy
+func (s *FrameScanner) Write(p []byte) (int, error) {
+	if s.start > 0 && s.start >= len(s.buf)/2 {
+		s.buf = s.buf[:copy(s.buf, s.buf[s.start:])]
+		s.start = 0
+	}
+	s.buf = append(s.buf, p...)
+	return len(p), nil
+}
+
+// Next returns the next complete frame written, nil if more data is needed.
+// The frame is valid until the next call of Write.
+func (s *FrameScanner) Next() []byte {
+	advance, token, _ := s.split(s.buf[s.start:], false, s.maxSize)
+	s.start += advance
+	return token
+}
+
+// Buffered returns the number of bytes written but not consumed yet
+func (s *FrameScanner) Buffered() int {
+	return len(s.buf) - s.start
+}
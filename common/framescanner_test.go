@@ -0,0 +1,177 @@
+package common_test
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/newkedison/go-utils/common"
+	"github.com/newkedison/go-utils/internal/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func makeStream() ([]byte, [][]byte) {
+	frames := [][]byte{
+		makeFrame([]byte("first")),
+		makeFrame(make([]byte, 200)),
+		makeFrame(nil),
+	}
+	bad := makeFrame([]byte("bad"))
+	bad[len(bad)-1] ^= 0x01
+	var stream []byte
+	stream = append(stream, 0xFF, 0xC0, 0x00) // garbage
+	stream = append(stream, frames[0]...)
+	stream = append(stream, bad...)
+	stream = append(stream, frames[1]...)
+	stream = append(stream, frames[2]...)
+	stream = append(stream, 0x05, 0x01) // incomplete frame at the end
+	return stream, frames
+}
+
+func TestFrameScannerSplit(t *testing.T) {
+	assert := assert.New(t)
+	stream, frames := makeStream()
+	s := common.NewFrameScanner()
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	scanner.Split(s.Split)
+	var tokens [][]byte
+	for scanner.Scan() {
+		tokens = append(tokens, append([]byte(nil), scanner.Bytes()...))
+	}
+	assert.Nil(scanner.Err())
+	assert.Equal(tokens, frames)
+	stats := s.Stats()
+	assert.EqualValues(stats.Frames, 3)
+	assert.EqualValues(stats.DiscardedBytes, 3+len(makeFrame([]byte("bad")))+2)
+	assert.True(stats.CrcErrors >= 1)
+}
+
+func TestFrameScannerChunks(t *testing.T) {
+	assert := assert.New(t)
+	stream, frames := makeStream()
+	for _, chunkSize := range []int{1, 2, 7, 64, len(stream)} {
+		s := common.NewFrameScanner()
+		var result [][]byte
+		for i := 0; i < len(stream); i += chunkSize {
+			end := i + chunkSize
+			if end > len(stream) {
+				end = len(stream)
+			}
+			n, err := s.Write(stream[i:end])
+			assert.Equal(n, end-i)
+			assert.Nil(err)
+			for frame := s.Next(); frame != nil; frame = s.Next() {
+				result = append(result, append([]byte(nil), frame...))
+			}
+		}
+		assert.Equal(result, frames, "chunk size %d", chunkSize)
+		assert.Equal(s.Buffered(), 2)
+		assert.EqualValues(s.Stats().Frames, 3)
+	}
+}
+
+func TestFrameScannerMessage(t *testing.T) {
+	assert := assert.New(t)
+	pb, _ := common.MarshalProtoMessage(common.NewNumber(3).ToProtoMessage())
+	s := common.NewFrameScanner()
+	s.Write(append([]byte{0x00, 0x01}, pb...))
+	frame := s.Next()
+	var n types.WSNumber
+	assert.Equal(common.UnmarshalProtoMessage(frame, &n), len(pb))
+	assert.EqualValues(n.Value, 3)
+	assert.Nil(s.Next())
+
+	s = common.NewFrameScanner()
+	s.SetMaxFrameSize(1)
+	s.Write(pb)
+	assert.Nil(s.Next())
+	assert.Equal(s.Buffered(), 0)
+	assert.EqualValues(s.Stats().DiscardedBytes, len(pb))
+}
+
+func TestFrameScannerTokenSize(t *testing.T) {
+	assert := assert.New(t)
+	var frames [][]byte
+	// a corrupt header of 65535 bytes, under the frame limit but over the
+	// token limit of bufio.Scanner
+	stream := []byte{0xC0, 0xFF, 0xFF}
+	for i := 0; i < 100; i++ {
+		frames = append(frames, makeFrame(make([]byte, 1000)))
+		stream = append(stream, frames[i]...)
+	}
+	s := common.NewFrameScanner()
+	s.SetMaxFrameSize(1 << 20)
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	scanner.Split(s.Split)
+	var tokens [][]byte
+	for scanner.Scan() {
+		tokens = append(tokens, append([]byte(nil), scanner.Bytes()...))
+	}
+	assert.Nil(scanner.Err())
+	assert.Equal(tokens, frames)
+	assert.EqualValues(s.Stats().DiscardedBytes, 3)
+
+	// with a larger buffer, larger frames are returned
+	var buf bytes.Buffer
+	w := common.NewFrameWriter(&buf)
+	w.SetMaxFrameSize(1 << 20)
+	assert.Nil(w.WriteFrame(make([]byte, 100000)))
+	frame := buf.Bytes()
+	s = common.NewFrameScanner()
+	s.SetMaxFrameSize(1 << 20)
+	s.SetMaxTokenSize(1 << 20)
+	scanner = bufio.NewScanner(bytes.NewReader(frame))
+	scanner.Buffer(nil, 1<<20)
+	scanner.Split(s.Split)
+	assert.True(scanner.Scan())
+	assert.Equal(scanner.Bytes(), frame)
+	assert.False(scanner.Scan())
+	assert.Nil(scanner.Err())
+}
+
+// scanFrames returns the tokens of s.Split on stream with a scanner buffer of
+// size bytes
+func scanFrames(s *common.FrameScanner, stream []byte,
+	size int) ([][]byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	scanner.Buffer(make([]byte, size), size)
+	scanner.Split(s.Split)
+	var tokens [][]byte
+	for scanner.Scan() {
+		tokens = append(tokens, append([]byte(nil), scanner.Bytes()...))
+	}
+	return tokens, scanner.Err()
+}
+
+func TestFrameScannerSmallLimits(t *testing.T) {
+	assert := assert.New(t)
+	small := makeFrame([]byte("0123456789"))
+	empty := makeFrame(nil)
+	stream := append(makeFrame(make([]byte, 100)), small...)
+	stream = append(stream, makeFrame([]byte("abc"))...)
+	stream = append(stream, empty...)
+
+	// a tiny buffer only holds the small frames
+	s := common.NewFrameScanner()
+	s.SetMaxTokenSize(16)
+	tokens, err := scanFrames(s, stream, 16)
+	assert.Nil(err)
+	assert.Equal(tokens, [][]byte{small, makeFrame([]byte("abc")), empty})
+
+	// a buffer smaller than a frame header only allows empty frames
+	s = common.NewFrameScanner()
+	s.SetMaxTokenSize(2)
+	tokens, err = scanFrames(s, stream, 16)
+	assert.Nil(err)
+	assert.Equal(tokens, [][]byte{empty})
+
+	// a negative limit is taken as 0
+	s = common.NewFrameScanner()
+	s.SetMaxFrameSize(-1)
+	tokens, err = scanFrames(s, stream, 16)
+	assert.Nil(err)
+	assert.Equal(tokens, [][]byte{empty})
+	s = common.NewFrameScanner()
+	s.SetMaxFrameSize(-1)
+	s.Write(stream)
+	assert.Equal(s.Next(), empty)
+}
@@ -112,7 +112,7 @@ func (v *Number) ToProtoMessage() *types.WSNumber {
 }
 
 func (v *Number) FromProtoMessage(p *types.WSNumber) {
-	*v = Number(p.Value)
+	*v = Number(p.GetValue())
 }
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface.
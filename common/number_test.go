@@ -330,3 +330,23 @@ func TestNumberUnmarshalBinary(t *testing.T) {
 	assert.EqualValues(n, 12)
 	assert.EqualValues(v, 1)
 }
+
+func FuzzNumberUnmarshalBinary(f *testing.F) {
+	data, _ := common.NewNumber(1).MarshalBinary()
+	f.Add(data)
+	f.Add([]byte{0x02, 0x03, 0x00, 0xD0, 0xF0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v common.Number
+		n, err := v.UnmarshalBinaryWithSize(data)
+		if err == nil && (n < 0 || n > len(data)) {
+			t.Fatalf("consumed %d of %d bytes", n, len(data))
+		}
+	})
+}
+
+func TestNumberFromNilProtoMessage(t *testing.T) {
+	assert := assert.New(t)
+	v := common.NewNumber(1)
+	v.FromProtoMessage(nil)
+	assert.EqualValues(*v, 0)
+}
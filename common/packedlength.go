@@ -1,81 +1,189 @@
 package common
 
 import (
+	"encoding/binary"
 	"errors"
+	"math"
 )
 
 var InvalidPackedLengthError error = errors.New("Invalid format for PackedLength")
 
-func PackLength(length uint32) ByteArray {
-	var ba ByteArray
-	AppendPackedLength(&ba, length)
-	return ba
+// ErrLengthOverflow is returned when a length can not be encoded by the
+// format of a LengthCodec
+var ErrLengthOverflow = errors.New("Length overflows PackedLength format")
+
+// PackedLengthFormat is the version of the wire format of a PackedLength
+type PackedLengthFormat int
+
+const (
+	// PackedLengthV1 is the format of PackLength: a byte for 0x00-0x7F,
+	// otherwise a prefix 0x80, 0xC0, 0xE0 or 0xF0 followed by 1 to 4 bytes
+	// of big endian length, up to 0xFFFFFFFF
+	PackedLengthV1 PackedLengthFormat = iota
+	// PackedLengthV2 extends PackedLengthV1 with a prefix 0xF8 followed by 8
+	// bytes for 64-bit lengths, every PackedLengthV1 is a valid PackedLengthV2
+	PackedLengthV2
+	// PackedLengthVarint is the unsigned varint (LEB128) of protobuf
+	PackedLengthVarint
+)
+
+type packedLengthPrefix struct {
+	prefix byte
+	size   int
+	// min is the smallest length using this prefix in canonical form
+	min uint64
 }
 
-func AppendPackedLength(dest *ByteArray, length uint32) {
+var packedLengthPrefixes = []packedLengthPrefix{
+	{0x80, 1, 0x80},
+	{0xC0, 2, 0x100},
+	{0xE0, 3, 0x10000},
+	{0xF0, 4, 0x1000000},
+	{0xF8, 8, 0x100000000},
+}
+
+// LengthCodec encodes and decodes the length before variable size data.
+// The zero value is the strict PackedLengthV1 used by PackLength.
+type LengthCodec struct {
+	Format PackedLengthFormat
+	// Lenient accepts non-canonical encodings, such as 0x80 0x05 for 5 or a
+	// varint with trailing 0x80 bytes, which are rejected by default
+	Lenient bool
+	// Max is the largest length accepted by Unpack, 0 for no limit. It guards
+	// the readers of untrusted data against huge lengths.
+	Max uint64
+}
+
+func (c LengthCodec) prefixes() []packedLengthPrefix {
+	if c.Format == PackedLengthV1 {
+		return packedLengthPrefixes[:4]
+	}
+	return packedLengthPrefixes
+}
+
+// MaxLength returns the largest length the format can encode
+func (c LengthCodec) MaxLength() uint64 {
+	if c.Format == PackedLengthV1 {
+		return math.MaxUint32
+	}
+	return math.MaxUint64
+}
+
+// Size returns the number of bytes used to encode length, 0 if it overflows
+// the format
+func (c LengthCodec) Size(length uint64) int {
+	if length > c.MaxLength() {
+		return 0
+	}
+	if c.Format == PackedLengthVarint {
+		size := 1
+		for ; length > 0x7F; length >>= 7 {
+			size++
+		}
+		return size
+	}
 	if length <= 0x7F {
-		dest.AppendByte(byte(length & 0xFF))
-	} else if length <= 0xFF {
-		dest.AppendByte(0x80)
-		dest.AppendByte(byte(length & 0xFF))
-	} else if length <= 0xFFFF {
-		dest.AppendByte(0xC0)
-		dest.AppendByte(byte((length >> 8) & 0xFF))
-		dest.AppendByte(byte(length & 0xFF))
-	} else if length <= 0xFFFFFF {
-		dest.AppendByte(0xE0)
-		dest.AppendByte(byte((length >> 16) & 0xFF))
-		dest.AppendByte(byte((length >> 8) & 0xFF))
-		dest.AppendByte(byte(length & 0xFF))
-	} else {
-		dest.AppendByte(0xF0)
-		dest.AppendByte(byte((length >> 24) & 0xFF))
-		dest.AppendByte(byte((length >> 16) & 0xFF))
-		dest.AppendByte(byte((length >> 8) & 0xFF))
-		dest.AppendByte(byte(length & 0xFF))
+		return 1
+	}
+	prefixes := c.prefixes()
+	for i, p := range prefixes[:len(prefixes)-1] {
+		if length < prefixes[i+1].min {
+			return p.size + 1
+		}
 	}
+	return prefixes[len(prefixes)-1].size + 1
 }
 
-func UnpackLength(data ByteArray) (uint32, error) {
-	if len(data) == 0 {
-		return 0, InvalidPackedLengthError
+// Append appends the encoded length to dst, ErrLengthOverflow is returned if
+// length overflows the format
+func (c LengthCodec) Append(dst []byte, length uint64) ([]byte, error) {
+	size := c.Size(length)
+	if size == 0 {
+		return dst, ErrLengthOverflow
 	}
-	if data[0] <= 0x7F {
-		return uint32(data[0]), nil
+	if c.Format == PackedLengthVarint {
+		for ; length > 0x7F; length >>= 7 {
+			dst = append(dst, byte(length)|0x80)
+		}
+		return append(dst, byte(length)), nil
 	}
-	length := len(data)
-	if length > 1 && data[0] == 0x80 && data[1] > 0x7F {
-		return uint32(data[1]), nil
+	if size == 1 {
+		return append(dst, byte(length)), nil
 	}
-	if length > 2 && data[0] == 0xC0 && data[1] > 0 {
-		return (uint32(data[1]) << 8) + uint32(data[2]), nil
+	for _, p := range c.prefixes() {
+		if p.size == size-1 {
+			dst = append(dst, p.prefix)
+			break
+		}
 	}
-	if length > 3 && data[0] == 0xE0 && data[1] > 0 {
-		return ((uint32)(data[1]) << 16) +
-			(uint32(data[2]) << 8) +
-			uint32(data[3]), nil
+	for i := size - 2; i >= 0; i-- {
+		dst = append(dst, byte(length>>(8*uint(i))))
 	}
-	if length > 4 && data[0] == 0xF0 && data[1] > 0 {
-		return ((uint32)(data[1]) << 24) +
-			(uint32(data[2]) << 16) +
-			(uint32(data[3]) << 8) +
-			uint32(data[4]), nil
+	return dst, nil
+}
+
+// Unpack decodes the length at the start of data and returns it with the
+// number of bytes consumed. InvalidPackedLengthError is returned if data is
+// too short or not a valid encoding, ErrLengthOverflow if the length is over
+// Max.
+func (c LengthCodec) Unpack(data []byte) (uint64, int, error) {
+	length, n, err := c.unpack(data)
+	if err == nil && c.Max > 0 && length > c.Max {
+		return 0, 0, ErrLengthOverflow
 	}
-	return 0, InvalidPackedLengthError
+	return length, n, err
 }
 
-func ByteCountOfPackedLength(length uint32) int {
-	if length <= 0x7F {
-		return 1
+func (c LengthCodec) unpack(data []byte) (uint64, int, error) {
+	if len(data) == 0 {
+		return 0, 0, InvalidPackedLengthError
 	}
-	if length <= 0xFF {
-		return 2
+	if c.Format == PackedLengthVarint {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || (!c.Lenient && n > 1 && data[n-1] == 0) {
+			return 0, 0, InvalidPackedLengthError
+		}
+		return length, n, nil
 	}
-	if length <= 0xFFFF {
-		return 3
+	if data[0] <= 0x7F {
+		return uint64(data[0]), 1, nil
 	}
-	if length <= 0xFFFFFF {
-		return 4
+	for _, p := range c.prefixes() {
+		if data[0] != p.prefix {
+			continue
+		}
+		if len(data) <= p.size {
+			break
+		}
+		var length uint64
+		for _, b := range data[1 : p.size+1] {
+			length = length<<8 | uint64(b)
+		}
+		if !c.Lenient && length < p.min {
+			break
+		}
+		return length, p.size + 1, nil
 	}
-	return 5
+	return 0, 0, InvalidPackedLengthError
+}
+
+func PackLength(length uint32) ByteArray {
+	var ba ByteArray
+	AppendPackedLength(&ba, length)
+	return ba
+}
+
+func AppendPackedLength(dest *ByteArray, length uint32) {
+	*dest, _ = LengthCodec{}.Append(*dest, uint64(length))
+}
+
+// UnpackLength returns the PackedLength at the start of data and the number
+// of bytes consumed
+func UnpackLength(data ByteArray) (uint32, int, error) {
+	length, n, err := LengthCodec{}.Unpack(data)
+	return uint32(length), n, err
+}
+
+func ByteCountOfPackedLength(length uint32) int {
+	return LengthCodec{}.Size(uint64(length))
 }
@@ -1,8 +1,11 @@
 package common_test
 
 import (
+	"bytes"
+	"encoding/binary"
 	"github.com/newkedison/go-utils/common"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
@@ -42,15 +45,17 @@ func TestAppendPackedLength(t *testing.T) {
 }
 
 func checkUnpackLengthValue(a *assert.Assertions, data []byte, expect uint32) {
-	l, err := common.UnpackLength(common.ByteArray(data))
+	l, n, err := common.UnpackLength(common.ByteArray(data))
 	a.Nil(err)
 	a.Equal(l, expect)
+	a.Equal(n, len(data))
 }
 
 func checkUnpackLengthError(a *assert.Assertions, data []byte) {
-	l, err := common.UnpackLength(common.ByteArray(data))
+	l, n, err := common.UnpackLength(common.ByteArray(data))
 	a.NotNil(err)
 	a.Equal(l, uint32(0))
+	a.Equal(n, 0)
 	a.Equal(err, common.InvalidPackedLengthError)
 }
 
@@ -105,3 +110,133 @@ func TestByteCountOfPackedLength(t *testing.T) {
 	assert.Equal(common.ByteCountOfPackedLength(0x1000000), 5)
 	assert.Equal(common.ByteCountOfPackedLength(0xFFFFFFFF), 5)
 }
+
+func TestUnpackLengthConsumed(t *testing.T) {
+	assert := assert.New(t)
+	l, n, err := common.UnpackLength(common.ByteArray{0xC0, 0x01, 0x00, 0xAA})
+	assert.Nil(err)
+	assert.Equal(l, uint32(0x100))
+	assert.Equal(n, 3)
+}
+
+func TestLengthCodecV2(t *testing.T) {
+	assert := assert.New(t)
+	codec := common.LengthCodec{Format: common.PackedLengthV2}
+	for _, length := range []uint64{0, 0x7F, 0x80, 0xFFFF, 0xFFFFFFFF} {
+		buf, err := codec.Append(nil, length)
+		assert.Nil(err)
+		assert.Equal(buf, []byte(common.PackLength(uint32(length))))
+	}
+	buf, err := codec.Append([]byte{0xAA}, 0x100000000)
+	assert.Nil(err)
+	assert.Equal(buf,
+		[]byte{0xAA, 0xF8, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00})
+	assert.Equal(codec.Size(0x100000000), 9)
+	assert.Equal(codec.Size(math.MaxUint64), 9)
+	l, n, err := codec.Unpack(buf[1:])
+	assert.Nil(err)
+	assert.Equal(l, uint64(0x100000000))
+	assert.Equal(n, 9)
+	_, _, err = codec.Unpack(buf[1:9])
+	assert.Equal(err, common.InvalidPackedLengthError)
+	_, _, err = codec.Unpack(
+		[]byte{0xF8, 0x00, 0x00, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF})
+	assert.Equal(err, common.InvalidPackedLengthError)
+
+	_, err = common.LengthCodec{}.Append(nil, 0x100000000)
+	assert.Equal(err, common.ErrLengthOverflow)
+	assert.Equal(common.LengthCodec{}.Size(0x100000000), 0)
+	_, _, err = common.LengthCodec{}.Unpack(buf[1:])
+	assert.Equal(err, common.InvalidPackedLengthError)
+}
+
+func TestLengthCodecVarint(t *testing.T) {
+	assert := assert.New(t)
+	codec := common.LengthCodec{Format: common.PackedLengthVarint}
+	for _, length := range []uint64{0, 1, 0x7F, 0x80, 300, 0xFFFFFFFF,
+		math.MaxUint64} {
+		buf, err := codec.Append(nil, length)
+		assert.Nil(err)
+		expected := make([]byte, binary.MaxVarintLen64)
+		expected = expected[:binary.PutUvarint(expected, length)]
+		assert.Equal(buf, expected)
+		assert.Equal(codec.Size(length), len(buf))
+		l, n, err := codec.Unpack(append(buf, 0x01))
+		assert.Nil(err)
+		assert.Equal(l, length)
+		assert.Equal(n, len(buf))
+	}
+	_, _, err := codec.Unpack([]byte{0x80})
+	assert.Equal(err, common.InvalidPackedLengthError)
+	_, _, err = codec.Unpack(bytes.Repeat([]byte{0xFF}, 11))
+	assert.Equal(err, common.InvalidPackedLengthError)
+	_, _, err = codec.Unpack([]byte{0x85, 0x00})
+	assert.Equal(err, common.InvalidPackedLengthError)
+	codec.Lenient = true
+	l, n, err := codec.Unpack([]byte{0x85, 0x80, 0x00})
+	assert.Nil(err)
+	assert.Equal(l, uint64(5))
+	assert.Equal(n, 3)
+}
+
+func TestLengthCodecLenient(t *testing.T) {
+	assert := assert.New(t)
+	codec := common.LengthCodec{Lenient: true}
+	for _, data := range [][]byte{{0x80, 0x05}, {0xC0, 0x00, 0x05},
+		{0xE0, 0x00, 0x00, 0x05}, {0xF0, 0x00, 0x00, 0x00, 0x05}} {
+		l, n, err := codec.Unpack(data)
+		assert.Nil(err)
+		assert.Equal(l, uint64(5))
+		assert.Equal(n, len(data))
+	}
+	_, _, err := codec.Unpack([]byte{0xC0, 0x00})
+	assert.Equal(err, common.InvalidPackedLengthError)
+	_, _, err = codec.Unpack([]byte{0x90, 0x00})
+	assert.Equal(err, common.InvalidPackedLengthError)
+}
+
+func TestLengthCodecMax(t *testing.T) {
+	assert := assert.New(t)
+	c := common.LengthCodec{Max: 0x100}
+	length, n, err := c.Unpack([]byte{0xC0, 0x01, 0x00})
+	assert.Nil(err)
+	assert.EqualValues(length, 0x100)
+	assert.Equal(n, 3)
+	_, _, err = c.Unpack([]byte{0xC0, 0x01, 0x01})
+	assert.Equal(err, common.ErrLengthOverflow)
+	_, _, err = common.LengthCodec{Format: common.PackedLengthVarint,
+		Max: 10}.Unpack([]byte{0x0B})
+	assert.Equal(err, common.ErrLengthOverflow)
+}
+
+func FuzzLengthCodecUnpack(f *testing.F) {
+	f.Add([]byte{0x7F})
+	f.Add([]byte{0x80, 0x80})
+	f.Add([]byte{0xF0, 0xFF, 0xFF, 0xFF, 0xFF})
+	f.Add([]byte{0xF8, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	f.Add([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, format := range []common.PackedLengthFormat{
+			common.PackedLengthV1, common.PackedLengthV2,
+			common.PackedLengthVarint} {
+			for _, lenient := range []bool{false, true} {
+				c := common.LengthCodec{Format: format, Lenient: lenient}
+				length, n, err := c.Unpack(data)
+				if err != nil {
+					continue
+				}
+				if n < 1 || n > len(data) {
+					t.Fatalf("%+v consumed %d of %d bytes", c, n, len(data))
+				}
+				if lenient {
+					continue
+				}
+				encoded, err := c.Append(nil, length)
+				if err != nil || !bytes.Equal(encoded, data[:n]) {
+					t.Fatalf("%+v decoded %X as %d, encoded as %X", c, data[:n],
+						length, encoded)
+				}
+			}
+		}
+	})
+}
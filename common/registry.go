@@ -0,0 +1,265 @@
+package common
+
+import (
+	"errors"
+	"github.com/newkedison/go-utils/internal/types"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrDuplicateId  = errors.New("Duplicate data id")
+	ErrDataNotFound = errors.New("Data not found")
+	ErrNilData      = errors.New("Nil data")
+	// ErrValueRejected is returned for a value refused by SetValue, because
+	// it is out of range or a check write method returned false
+	ErrValueRejected = errors.New("Value rejected")
+)
+
+// DataPoint is the data held by a DataRegistry, it is implemented by
+// *NamedData and *SafeNamedData
+type DataPoint interface {
+	Id() string
+	Name() string
+	Value() Number
+	SetValue(Number) bool
+	ToProtoMessage() *types.WSData
+}
+
+// DataRegistry finds data by id. It can be used from multiple goroutines,
+// but calls to the data are only safe if the data is, such as a
+// SafeNamedData. All lists are sorted by id.
+type DataRegistry struct {
+	mu     sync.RWMutex
+	data   map[string]DataPoint
+	ids    []string
+	groups map[string]map[string]bool
+}
+
+func NewDataRegistry() *DataRegistry {
+	return &DataRegistry{
+		data:   make(map[string]DataPoint),
+		groups: make(map[string]map[string]bool),
+	}
+}
+
+// Register adds d to the registry and to groups, ErrDuplicateId is returned
+// if the id of d is already registered, ErrNilData if d is nil
+func (r *DataRegistry) Register(d DataPoint, groups ...string) error {
+	if d == nil {
+		return ErrNilData
+	}
+	if v := reflect.ValueOf(d); v.Kind() == reflect.Ptr && v.IsNil() {
+		return ErrNilData
+	}
+	id := d.Id()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.data[id]; ok {
+		return ErrDuplicateId
+	}
+	r.data[id] = d
+	i := sort.SearchStrings(r.ids, id)
+	r.ids = append(r.ids, "")
+	copy(r.ids[i+1:], r.ids[i:])
+	r.ids[i] = id
+	for _, g := range groups {
+		r.addToGroup(g, id)
+	}
+	return nil
+}
+
+// Unregister removes the data of id from the registry and its groups, it
+// returns false if id is not registered
+func (r *DataRegistry) Unregister(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.data[id]; !ok {
+		return false
+	}
+	delete(r.data, id)
+	i := sort.SearchStrings(r.ids, id)
+	r.ids = append(r.ids[:i], r.ids[i+1:]...)
+	for name, members := range r.groups {
+		delete(members, id)
+		if len(members) == 0 {
+			delete(r.groups, name)
+		}
+	}
+	return true
+}
+
+func (r *DataRegistry) Get(id string) (DataPoint, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.data[id]
+	return d, ok
+}
+
+func (r *DataRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.ids)
+}
+
+func (r *DataRegistry) Ids() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.ids...)
+}
+
+// filter returns the data which id is accepted by match, which is called
+// with the read lock held
+func (r *DataRegistry) filter(match func(id string) bool) []DataPoint {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var result []DataPoint
+	for _, id := range r.ids {
+		if match(id) {
+			result = append(result, r.data[id])
+		}
+	}
+	return result
+}
+
+// All returns all the data
+func (r *DataRegistry) All() []DataPoint {
+	return r.filter(func(string) bool { return true })
+}
+
+// Range calls f for each data until f returns false. The registry is not
+// locked during the calls, so f can register or unregister data.
+func (r *DataRegistry) Range(f func(DataPoint) bool) {
+	for _, d := range r.All() {
+		if !f(d) {
+			return
+		}
+	}
+}
+
+// Match returns the data which id matches the pattern of path.Match, such
+// as "boiler/*/temp". path.ErrBadPattern is returned for a bad pattern.
+func (r *DataRegistry) Match(pattern string) ([]DataPoint, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return r.filter(func(id string) bool {
+		ok, _ := path.Match(pattern, id)
+		return ok
+	}), nil
+}
+
+func (r *DataRegistry) WithPrefix(prefix string) []DataPoint {
+	return r.filter(func(id string) bool {
+		return strings.HasPrefix(id, prefix)
+	})
+}
+
+func (r *DataRegistry) addToGroup(group, id string) {
+	members, ok := r.groups[group]
+	if !ok {
+		members = make(map[string]bool)
+		r.groups[group] = members
+	}
+	members[id] = true
+}
+
+// AddToGroup adds the data of ids to group, ErrDataNotFound is returned
+// without changing the group if an id is not registered
+func (r *DataRegistry) AddToGroup(group string, ids ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := r.data[id]; !ok {
+			return ErrDataNotFound
+		}
+	}
+	for _, id := range ids {
+		r.addToGroup(group, id)
+	}
+	return nil
+}
+
+// RemoveFromGroup removes the data of ids from group, an empty group is
+// deleted
+func (r *DataRegistry) RemoveFromGroup(group string, ids ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	members := r.groups[group]
+	for _, id := range ids {
+		delete(members, id)
+	}
+	if len(members) == 0 {
+		delete(r.groups, group)
+	}
+}
+
+func (r *DataRegistry) Group(group string) []DataPoint {
+	return r.filter(func(id string) bool { return r.groups[group][id] })
+}
+
+func (r *DataRegistry) Groups() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	groups := make([]string, 0, len(r.groups))
+	for g := range r.groups {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// setValue sets the value of id, it returns ErrDataNotFound or
+// ErrValueRejected on failure
+func (r *DataRegistry) setValue(id string, v Number) error {
+	d, ok := r.Get(id)
+	switch {
+	case !ok:
+		return ErrDataNotFound
+	case !d.SetValue(v):
+		return ErrValueRejected
+	}
+	return nil
+}
+
+// SetValues sets the value of each id in the order of the ids, so the
+// callbacks of the data are called in a known order. The result has an entry
+// for each id: nil on success, ErrDataNotFound or ErrValueRejected otherwise.
+func (r *DataRegistry) SetValues(values map[string]Number) map[string]error {
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	result := make(map[string]error, len(values))
+	for _, id := range ids {
+		result[id] = r.setValue(id, values[id])
+	}
+	return result
+}
+
+// Snapshot returns the proto message of all the data
+func (r *DataRegistry) Snapshot() []*types.WSData {
+	all := r.All()
+	result := make([]*types.WSData, len(all))
+	for i, d := range all {
+		result[i] = d.ToProtoMessage()
+	}
+	return result
+}
+
+// Restore sets the values of a Snapshot in its order, other fields of the
+// messages are ignored. The result is the same as SetValues, the last
+// message of an id gives its entry.
+func (r *DataRegistry) Restore(snapshot []*types.WSData) map[string]error {
+	result := make(map[string]error, len(snapshot))
+	for _, p := range snapshot {
+		var v Number
+		v.FromProtoMessage(p.GetValue())
+		result[p.GetId()] = r.setValue(p.GetId(), v)
+	}
+	return result
+}
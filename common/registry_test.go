@@ -0,0 +1,191 @@
+package common_test
+
+import (
+	"github.com/newkedison/go-utils/common"
+	"github.com/stretchr/testify/assert"
+	"path"
+	"sync"
+	"testing"
+)
+
+func newTestRegistry(assert *assert.Assertions) *common.DataRegistry {
+	r := common.NewDataRegistry()
+	for _, id := range []string{"boiler/1/temp", "boiler/2/temp",
+		"boiler/1/pressure", "pump/speed"} {
+		d := common.NewSafeNamedData(id, id, 0, common.NewRange(0, 100))
+		assert.Nil(r.Register(d))
+	}
+	return r
+}
+
+func dataIds(data []common.DataPoint) []string {
+	var result []string
+	for _, d := range data {
+		result = append(result, d.Id())
+	}
+	return result
+}
+
+func TestDataRegistry(t *testing.T) {
+	assert := assert.New(t)
+	r := newTestRegistry(assert)
+	assert.Equal(r.Len(), 4)
+	assert.Equal(r.Ids(), []string{"boiler/1/pressure", "boiler/1/temp",
+		"boiler/2/temp", "pump/speed"})
+	d := common.NewNamedData("pump/speed", "", 0, common.NewRange(0, 1))
+	assert.Equal(r.Register(&d), common.ErrDuplicateId)
+	d2 := common.NewNamedData("fan", "", 0, common.NewRange(0, 1))
+	assert.Nil(r.Register(&d2))
+	assert.Equal(r.Register(nil), common.ErrNilData)
+	assert.Equal(r.Register((*common.SafeNamedData)(nil)), common.ErrNilData)
+
+	found, ok := r.Get("fan")
+	assert.True(ok)
+	assert.Equal(found, &d2)
+	_, ok = r.Get("none")
+	assert.False(ok)
+
+	assert.True(r.Unregister("fan"))
+	assert.False(r.Unregister("fan"))
+	assert.Equal(r.Len(), 4)
+
+	var visited []string
+	r.Range(func(d common.DataPoint) bool {
+		visited = append(visited, d.Id())
+		return len(visited) < 2
+	})
+	assert.Equal(visited, []string{"boiler/1/pressure", "boiler/1/temp"})
+}
+
+func TestDataRegistryQuery(t *testing.T) {
+	assert := assert.New(t)
+	r := newTestRegistry(assert)
+	found, err := r.Match("boiler/*/temp")
+	assert.Nil(err)
+	assert.Equal(dataIds(found), []string{"boiler/1/temp", "boiler/2/temp"})
+	_, err = r.Match("[")
+	assert.Equal(err, path.ErrBadPattern)
+	assert.Equal(dataIds(r.WithPrefix("boiler/1/")),
+		[]string{"boiler/1/pressure", "boiler/1/temp"})
+	assert.Nil(r.WithPrefix("fan"))
+}
+
+func TestDataRegistryGroup(t *testing.T) {
+	assert := assert.New(t)
+	r := newTestRegistry(assert)
+	d := common.NewNamedData("fan", "", 0, common.NewRange(0, 1))
+	assert.Nil(r.Register(&d, "motors"))
+	assert.Nil(r.AddToGroup("motors", "pump/speed"))
+	assert.Nil(r.AddToGroup("temps", "boiler/2/temp", "boiler/1/temp"))
+	assert.Equal(r.AddToGroup("temps", "none"), common.ErrDataNotFound)
+	assert.Equal(r.Groups(), []string{"motors", "temps"})
+	assert.Equal(dataIds(r.Group("motors")), []string{"fan", "pump/speed"})
+	assert.Equal(dataIds(r.Group("temps")), []string{"boiler/1/temp",
+		"boiler/2/temp"})
+	assert.Nil(r.Group("none"))
+
+	r.RemoveFromGroup("temps", "boiler/1/temp")
+	assert.Equal(dataIds(r.Group("temps")), []string{"boiler/2/temp"})
+	r.Unregister("boiler/2/temp")
+	assert.Equal(r.Groups(), []string{"motors"})
+	r.RemoveFromGroup("motors", "fan", "pump/speed")
+	assert.Equal(r.Groups(), []string{})
+}
+
+func TestDataRegistrySetValues(t *testing.T) {
+	assert := assert.New(t)
+	r := newTestRegistry(assert)
+	result := r.SetValues(map[string]common.Number{
+		"boiler/1/temp": 50,
+		"pump/speed":    200,
+		"none":          1,
+	})
+	assert.Equal(result, map[string]error{
+		"boiler/1/temp": nil,
+		"pump/speed":    common.ErrValueRejected,
+		"none":          common.ErrDataNotFound,
+	})
+	d, _ := r.Get("boiler/1/temp")
+	assert.EqualValues(d.Value(), 50)
+}
+
+// recordModified returns the ids of the data of r in the order their values
+// are modified
+func recordModified(r *common.DataRegistry) func() []string {
+	var ids []string
+	r.Range(func(d common.DataPoint) bool {
+		d.(*common.SafeNamedData).OnModified(func(data *common.NamedData,
+			_, _ common.Number) {
+			ids = append(ids, data.Id())
+		})
+		return true
+	})
+	return func() []string { return ids }
+}
+
+func TestDataRegistrySetValuesOrder(t *testing.T) {
+	assert := assert.New(t)
+	r := newTestRegistry(assert)
+	modified := recordModified(r)
+	r.SetValues(map[string]common.Number{"pump/speed": 1, "boiler/2/temp": 1,
+		"boiler/1/temp": 1, "boiler/1/pressure": 1})
+	assert.Equal(modified(), []string{"boiler/1/pressure", "boiler/1/temp",
+		"boiler/2/temp", "pump/speed"})
+
+	// Restore follows the order of the snapshot
+	snapshot := r.Snapshot()
+	snapshot[0], snapshot[3] = snapshot[3], snapshot[0]
+	r2 := newTestRegistry(assert)
+	modified = recordModified(r2)
+	r2.Restore(snapshot)
+	assert.Equal(modified(), []string{"pump/speed", "boiler/1/temp",
+		"boiler/2/temp", "boiler/1/pressure"})
+}
+
+func TestDataRegistrySnapshot(t *testing.T) {
+	assert := assert.New(t)
+	r := newTestRegistry(assert)
+	r.SetValues(map[string]common.Number{"boiler/1/temp": 50, "pump/speed": 10})
+	snapshot := r.Snapshot()
+	assert.Len(snapshot, 4)
+	assert.Equal(snapshot[1].Id, "boiler/1/temp")
+	assert.EqualValues(snapshot[1].Value.Value, 50)
+
+	r2 := newTestRegistry(assert)
+	r2.Unregister("boiler/2/temp")
+	result := r2.Restore(snapshot)
+	assert.Nil(result["boiler/1/temp"])
+	assert.Nil(result["pump/speed"])
+	assert.Equal(result["boiler/2/temp"], common.ErrDataNotFound)
+	d, _ := r2.Get("pump/speed")
+	assert.EqualValues(d.Value(), 10)
+}
+
+func TestDataRegistryConcurrent(t *testing.T) {
+	assert := assert.New(t)
+	r := newTestRegistry(assert)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				id := string(rune('a'+i)) + string(rune('a'+j%26))
+				d := common.NewSafeNamedData(id, id, 0, common.NewRange(0, 1))
+				r.Register(d, "group")
+				r.SetValues(map[string]common.Number{id: 1, "pump/speed": 2})
+				r.Unregister(id)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				r.Group("group")
+				r.Snapshot()
+				r.Match("*")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(r.Len(), 4)
+}
@@ -0,0 +1,262 @@
+package common
+
+import (
+	"errors"
+	"github.com/newkedison/go-utils/internal/types"
+	"sync"
+)
+
+// SafeNamedData is a NamedData which can be used from multiple goroutines.
+// The callbacks are called without holding the lock, so they can call the
+// methods of the SafeNamedData. The *NamedData and *Alarm they receive are
+// snapshots taken when the signal fired, changing them does not change the
+// SafeNamedData.
+//
+// The check write methods are also called without the lock, on a snapshot.
+// If the data is changed by another goroutine before the lock is taken, they
+// are called again on the new data, so a write is only done if they accept
+// the current data. They must not have side effects which depend on the
+// number of calls.
+type SafeNamedData struct {
+	mu   sync.RWMutex
+	data NamedData
+	// version is changed by every write, so a write checked on an older
+	// snapshot is checked again
+	version uint64
+}
+
+// alarmEvent is an alarm signal to fire after the lock is released
+type alarmEvent struct {
+	sig   SignalAlarm
+	value Number
+	alarm Alarm
+}
+
+func NewSafeNamedData(id string, name string, initValue Number,
+	dataRange Range) *SafeNamedData {
+	s := &SafeNamedData{data: NewNamedData(id, name, initValue, dataRange)}
+	// the alarms must watch the value of the NamedData in s
+	s.data.initAlarms()
+	return s
+}
+
+// snapshot returns a copy of the data, its signals are called on the copy
+func (s *SafeNamedData) snapshot() NamedData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data
+}
+
+// lock takes the write lock for a change of the data
+func (s *SafeNamedData) lock() {
+	s.mu.Lock()
+	s.version++
+}
+
+// lockChecked takes the write lock once check accepts a snapshot which is
+// still the current data, check is called without the lock so it can call
+// the callbacks. It returns false without the lock if check fails.
+func (s *SafeNamedData) lockChecked(check func(*NamedData) bool) bool {
+	for {
+		s.mu.RLock()
+		snap, version := s.data, s.version
+		s.mu.RUnlock()
+		if !check(&snap) {
+			return false
+		}
+		s.mu.Lock()
+		if s.version == version {
+			s.version++
+			return true
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *SafeNamedData) Id() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.id
+}
+
+func (s *SafeNamedData) Name() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.name
+}
+
+func (s *SafeNamedData) SetName(name string) {
+	s.lock()
+	defer s.mu.Unlock()
+	s.data.name = name
+}
+
+func (s *SafeNamedData) Range() Range {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.dataRange
+}
+
+// SetRane is like NamedData.SetRane
+func (s *SafeNamedData) SetRane(r Range) bool {
+	if !s.lockChecked(func(d *NamedData) bool {
+		return d.IsWritable(d.value)
+	}) {
+		return false
+	}
+	origin := s.data.dataRange
+	s.data.dataRange.Change(r.low, r.high)
+	oldValue := s.data.value
+	if s.data.value < r.low {
+		s.data.value = r.low
+	} else if s.data.value > r.high {
+		s.data.value = r.high
+	}
+	snap := s.data
+	s.mu.Unlock()
+	snap.sigRangeModified.Fire(RangeModifiedArgs{origin, r})
+	if oldValue != snap.value {
+		snap.sigModified.Fire(
+			DataModifiedArgs{&snap, oldValue, snap.value})
+	}
+	return true
+}
+
+// Value is like NamedData.Value
+func (s *SafeNamedData) Value() Number {
+	snap := s.snapshot()
+	return snap.Value()
+}
+
+// SetValue is like NamedData.SetValue, the alarms are checked in the same
+// critical section as the value is set
+func (s *SafeNamedData) SetValue(newValue Number) bool {
+	if !s.lockChecked(func(d *NamedData) bool {
+		return d.IsWritable(newValue)
+	}) {
+		return false
+	}
+	if newValue < s.data.dataRange.low || newValue > s.data.dataRange.high {
+		s.mu.Unlock()
+		return false
+	}
+	oldValue := s.data.value
+	s.data.value = newValue
+	var events []alarmEvent
+	if s.data.autoCheck {
+		for _, a := range []*Alarm{&s.data.warning, &s.data.fault} {
+			if sig := a.check(); sig != nil {
+				events = append(events, alarmEvent{*sig, newValue, *a})
+			}
+		}
+	}
+	snap := s.data
+	s.mu.Unlock()
+	snap.sigModified.Fire(DataModifiedArgs{&snap, oldValue, newValue})
+	for i := range events {
+		events[i].sig.Fire(AlarmArgs{events[i].value, &events[i].alarm})
+	}
+	return true
+}
+
+func (s *SafeNamedData) IsAutoCheck() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.autoCheck
+}
+
+func (s *SafeNamedData) SetAutoCheck(b bool) {
+	s.lock()
+	defer s.mu.Unlock()
+	s.data.autoCheck = b
+}
+
+func (s *SafeNamedData) IsReadable() bool {
+	snap := s.snapshot()
+	return snap.IsReadable()
+}
+
+func (s *SafeNamedData) IsWritable(newValue Number) bool {
+	snap := s.snapshot()
+	return snap.IsWritable(newValue)
+}
+
+func (s *SafeNamedData) OnModified(f func(*NamedData, Number, Number),
+	opts ...ConnectOption) *Connection {
+	s.lock()
+	defer s.mu.Unlock()
+	return s.data.OnModified(f, opts...)
+}
+
+func (s *SafeNamedData) OnRangeModified(f func(Range, Range),
+	opts ...ConnectOption) *Connection {
+	s.lock()
+	defer s.mu.Unlock()
+	return s.data.OnRangeModified(f, opts...)
+}
+
+func (s *SafeNamedData) AddCheckReadMethod(f func(*NamedData, Number) bool,
+	opts ...ConnectOption) *Connection {
+	s.lock()
+	defer s.mu.Unlock()
+	return s.data.AddCheckReadMethod(f, opts...)
+}
+
+func (s *SafeNamedData) AddCheckWriteMethod(f func(*NamedData, Number) bool,
+	opts ...ConnectOption) *Connection {
+	s.lock()
+	defer s.mu.Unlock()
+	return s.data.AddCheckWriteMethod(f, opts...)
+}
+
+// View calls f with the data under the read lock, f must not change the data
+// or call the methods of s
+func (s *SafeNamedData) View(f func(*NamedData)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f(&s.data)
+}
+
+// Update calls f with the data under the write lock, such as to configure
+// the alarms. f must not call the methods of s, and the callbacks fired by f
+// are called with the lock held.
+func (s *SafeNamedData) Update(f func(*NamedData)) {
+	s.lock()
+	defer s.mu.Unlock()
+	f(&s.data)
+}
+
+func (s *SafeNamedData) ToProtoMessage() *types.WSData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.ToProtoMessage()
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (s *SafeNamedData) MarshalBinary() (result []byte, err error) {
+	defer SetErrorWhenMarshalObjectErrorPanic("common.SafeNamedData", &err)()
+	return MarshalProtoMessage(s.ToProtoMessage())
+}
+
+// UnmarshalBinaryWithSize implements the common.BinaryUnmarshalerWithSize interface.
+func (s *SafeNamedData) UnmarshalBinaryWithSize(data []byte) (_ int, err error) {
+	defer SetErrorWhenUnmarshalObjectErrorPanic("common.SafeNamedData", &err)()
+	var id string
+	if !s.lockChecked(func(d *NamedData) bool {
+		id = d.id
+		return d.IsWritable(d.value)
+	}) {
+		panic(NewUnmarshalObjectError(errors.New("Unmarshal " + id + " fail: not writable.")))
+	}
+	defer s.mu.Unlock()
+	var result types.WSData
+	used := UnmarshalProtoMessage(data, &result)
+	s.data.FromProtoMessage(&result)
+	return used, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (s *SafeNamedData) UnmarshalBinary(data []byte) error {
+	_, err := s.UnmarshalBinaryWithSize(data)
+	return err
+}
@@ -0,0 +1,163 @@
+package common_test
+
+import (
+	"github.com/newkedison/go-utils/common"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestSafeNamedData(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 100, common.NewRange(0, 100))
+	assert.Equal(d.Id(), "id")
+	assert.Equal(d.Name(), "name")
+	d.SetName("aaa")
+	assert.Equal(d.Name(), "aaa")
+	assert.EqualValues(d.Value(), 100)
+	assert.False(d.SetValue(101))
+	assert.True(d.SetValue(50))
+	assert.EqualValues(d.Value(), 50)
+	assert.True(d.SetRane(common.NewRange(10, 20)))
+	assert.Equal(d.Range(), common.NewRange(10, 20))
+	assert.EqualValues(d.Value(), 20)
+
+	d.AddCheckReadMethod(func(*common.NamedData, common.Number) bool {
+		return false
+	})
+	assert.False(d.IsReadable())
+	assert.Equal(d.Value(), common.InvalidValue)
+	d.AddCheckWriteMethod(func(_ *common.NamedData, v common.Number) bool {
+		return v != 15
+	})
+	assert.True(d.IsWritable(16))
+	assert.False(d.SetValue(15))
+	assert.True(d.SetValue(16))
+}
+
+func TestSafeNamedDataSignals(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 100, common.NewRange(0, 100))
+	var modified []common.Number
+	d.OnModified(func(data *common.NamedData, _, newValue common.Number) {
+		// the lock is not held, so the callback can use d
+		assert.EqualValues(d.Value(), newValue)
+		assert.EqualValues(data.Value(), newValue)
+		modified = append(modified, newValue)
+	})
+	var ranges []common.Range
+	d.OnRangeModified(func(_, r common.Range) {
+		ranges = append(ranges, d.Range())
+	})
+	d.SetValue(50)
+	d.SetRane(common.NewRange(0, 10))
+	assert.Equal(modified, []common.Number{50, 10})
+	assert.Equal(ranges, []common.Range{common.NewRange(0, 10)})
+}
+
+func TestSafeNamedDataAlarm(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 50, common.NewRange(0, 100))
+	var alarms []common.AlarmState
+	d.Update(func(data *common.NamedData) {
+		data.Warning().SetRange(common.NewRange(20, 80)).Enable()
+		data.Warning().OnAlarm(func(v common.Number, a *common.Alarm) {
+			assert.EqualValues(d.Value(), v)
+			alarms = append(alarms, a.State())
+		}).OnAlarmCanceled(func(v common.Number, a *common.Alarm) {
+			alarms = append(alarms, a.State())
+		})
+	})
+	d.SetAutoCheck(true)
+	assert.True(d.IsAutoCheck())
+	d.SetValue(90)
+	d.SetValue(10)
+	d.SetValue(50)
+	assert.Equal(alarms, []common.AlarmState{common.OverFlow,
+		common.UnderFlow, common.AutoCanceled})
+	d.View(func(data *common.NamedData) {
+		assert.Equal(data.Warning().State(), common.AutoCanceled)
+		assert.EqualValues(data.Warning().LastAlarmValue(), 10)
+	})
+}
+
+func TestSafeNamedDataMarshalBinary(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 50, common.NewRange(0, 100))
+	data, err := d.MarshalBinary()
+	assert.Nil(err)
+	d2 := common.NewSafeNamedData("", "", 0, common.NewRange(0, 0))
+	assert.Nil(d2.UnmarshalBinary(data))
+	assert.Equal(d2.Id(), "id")
+	assert.EqualValues(d2.Value(), 50)
+	assert.Equal(d2.Range(), common.NewRange(0, 100))
+	assert.NotNil(d2.UnmarshalBinary(data[1:]))
+	d2.AddCheckWriteMethod(func(*common.NamedData, common.Number) bool {
+		return false
+	})
+	assert.NotNil(d2.UnmarshalBinary(data))
+}
+
+func TestSafeNamedDataConcurrent(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 0, common.NewRange(0, 1000))
+	d.Update(func(data *common.NamedData) {
+		data.Warning().SetRange(common.NewRange(0, 500)).Enable()
+	})
+	d.SetAutoCheck(true)
+	var mu sync.Mutex
+	count := 0
+	d.OnModified(func(*common.NamedData, common.Number, common.Number) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				d.SetValue(common.Number(i*250 + j))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				d.Value()
+				d.Range()
+				d.ToProtoMessage()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				d.OnRangeModified(func(common.Range, common.Range) {})
+				d.SetRane(common.NewRange(0, 1000))
+				d.View(func(data *common.NamedData) {
+					data.Warning().State()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(count, 400)
+}
+
+func TestSafeNamedDataCheckWriteRace(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 0, common.NewRange(0, 100))
+	calls := 0
+	// only a value of 0 can be changed, the first check lets another write
+	// happen after the snapshot is taken
+	d.AddCheckWriteMethod(func(data *common.NamedData, _ common.Number) bool {
+		calls++
+		if calls == 1 {
+			assert.True(d.SetValue(7))
+		}
+		return data.Value() == 0
+	})
+	assert.False(d.SetValue(5))
+	assert.EqualValues(d.Value(), 7)
+	assert.Equal(calls, 3)
+}
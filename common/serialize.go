@@ -1,19 +1,33 @@
 package common
 
 import (
-	"bytes"
 	"encoding"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	proto "github.com/golang/protobuf/proto"
 	"github.com/newkedison/go-utils/algorithm"
-	"math"
 	"reflect"
+	"time"
 )
 
 var defaultByteOrder binary.ByteOrder = binary.LittleEndian
 
+// protoMessageCrc is the checksum of MarshalProtoMessage, it is part of the
+// wire format so it is pinned here instead of using the package default
+var protoMessageCrc = algorithm.MustNewCrc(algorithm.Config{
+	Name:       "CRC-16/MODBUS",
+	Width:      16,
+	Polynomial: 0x8005,
+	InitValue:  0xFFFF,
+	ReflectIn:  true,
+	ReflectOut: true,
+	Check:      0x4B37,
+})
+
+// SetByteOrder changes the order of the package level functions for the
+// whole process, use an Encoder and a Decoder to talk to devices with
+// different orders
 func SetByteOrder(o binary.ByteOrder) {
 	defaultByteOrder = o
 }
@@ -25,10 +39,49 @@ type BinaryUnmarshalerWithSize interface {
 type MarshalObjectError error
 type UnmarshalObjectError error
 
+var (
+	// ErrShortBuffer matches any *ShortBufferError with errors.Is
+	ErrShortBuffer = errors.New("Not enought data")
+	ErrCrcMismatch = errors.New("CRC check fail")
+	ErrUnknownType = errors.New("Unknown type")
+	// ErrTimeOverflow is returned for a time.Time which count of the time
+	// unit does not fit in an int64, such as the zero time in nanoseconds
+	ErrTimeOverflow = errors.New("Time overflows the time unit")
+	// ErrStringTooLong is returned for strings which length does not fit in
+	// the length prefix or the width of their format
+	ErrStringTooLong = errors.New("String too long")
+)
+
+// ShortBufferError reports that Offered-Offset bytes are left but Required
+// bytes are needed
+type ShortBufferError struct {
+	Required int
+	Offered  int
+	Offset   int
+}
+
+func (e *ShortBufferError) Error() string {
+	return fmt.Sprintf("Not enought data, require %d, offer %d-%d=%d",
+		e.Required, e.Offered, e.Offset, e.Offered-e.Offset)
+}
+
+func (e *ShortBufferError) Is(target error) bool {
+	return target == ErrShortBuffer
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// shortBufferOf returns the *ShortBufferError of a length read from data,
+// which may not fit in an int
+func shortBufferOf(length uint64, data []byte, offset int) *ShortBufferError {
+	if length > uint64(maxInt) {
+		length = uint64(maxInt)
+	}
+	return &ShortBufferError{int(length), len(data), offset}
+}
+
 func NewNotEnoughDataError(required, offer, offset int) UnmarshalObjectError {
-	return UnmarshalObjectError(errors.New(fmt.Sprintf(
-		"Not enought data, require %d, offer %d-%d=%d",
-		required, offer, offset, offer-offset)))
+	return UnmarshalObjectError(&ShortBufferError{required, offer, offset})
 }
 
 func NewMarshalObjectError(err error) MarshalObjectError {
@@ -73,166 +126,221 @@ func CheckBufferSize(buf []byte, requiredLength int, args ...interface{}) {
 			offset = reflect.ValueOf(args[0]).Convert(offsetType).Interface().(int)
 		}
 	}
-	if len(buf) < offset+requiredLength {
-		panic(NewNotEnoughDataError(requiredLength, len(buf), offset))
+	if err := CheckBuffer(buf, requiredLength, offset); err != nil {
+		panic(NewUnmarshalObjectError(err))
+	}
+}
+
+// CheckBuffer returns a *ShortBufferError if buf has less than
+// requiredLength bytes after offset
+func CheckBuffer(buf []byte, requiredLength, offset int) error {
+	// compared without offset+requiredLength, which may overflow
+	if requiredLength < 0 || offset < 0 || requiredLength > len(buf)-offset {
+		return &ShortBufferError{requiredLength, len(buf), offset}
 	}
+	return nil
 }
 
+// MarshalSimpleType encodes d with the byte order set by SetByteOrder, see
+// Encoder for the simple types. A slice is prefixed by its PackedLength,
+// unlike a slice field of Marshal which has a u16 length prefix.
 func MarshalSimpleType(d interface{}) []byte {
-	tmp := make([]byte, 8)
-	switch v := d.(type) {
-	case byte:
-		return []byte{v}
-	case int8:
-		return []byte{byte(v)}
-	case int16:
-		defaultByteOrder.PutUint16(tmp, uint16(v))
-		return tmp[:2]
-	case uint16:
-		defaultByteOrder.PutUint16(tmp, v)
-		return tmp[:2]
-	case int:
-		defaultByteOrder.PutUint32(tmp, uint32(v))
-		return tmp[:4]
-	case uint:
-		defaultByteOrder.PutUint32(tmp, uint32(v))
-		return tmp[:4]
-	case int32:
-		defaultByteOrder.PutUint32(tmp, uint32(v))
-		return tmp[:4]
-	case uint32:
-		defaultByteOrder.PutUint32(tmp, v)
-		return tmp[:4]
-	case int64:
-		defaultByteOrder.PutUint64(tmp, uint64(v))
-		return tmp
-	case uint64:
-		defaultByteOrder.PutUint64(tmp, v)
-		return tmp
-	case float32:
-		return MarshalSimpleType(math.Float32bits(v))
-	case float64:
-		return MarshalSimpleType(math.Float64bits(v))
-	}
-	panic(NewMarshalObjectError(errors.New("MarshalSimpleType: Unknown type")))
+	data, err := EncodeSimpleType(d)
+	if err != nil {
+		panic(NewMarshalObjectError(err))
+	}
+	return data
+}
+
+// EncodeSimpleType is like MarshalSimpleType but returns an error wrapping
+// ErrUnknownType instead of panic
+func EncodeSimpleType(d interface{}) ([]byte, error) {
+	return NewEncoder(defaultByteOrder).EncodeSimpleType(d)
+}
+
+// AppendSimpleType is like EncodeSimpleType but appends to dst, it does not
+// allocate if dst has enough capacity
+func AppendSimpleType(dst []byte, d interface{}) ([]byte, error) {
+	return NewEncoder(defaultByteOrder).AppendSimpleType(dst, d)
+}
+
+// AppendComposite is like AppendSimpleType but also accepts slices, arrays
+// and maps of simple types
+func AppendComposite(dst []byte, d interface{}) ([]byte, error) {
+	return NewEncoder(defaultByteOrder).AppendComposite(dst, d)
 }
 
 func UnmarshalSimpleType(p interface{}, data []byte) int {
-	t := reflect.TypeOf(p)
-	if t.Kind() == reflect.Ptr {
-		switch v := p.(type) {
-		case *byte:
-			CheckBufferSize(data, 1)
-			*v = data[0]
-			return 1
-		case *int8:
-			CheckBufferSize(data, 1)
-			*v = int8(data[0])
-			return 1
-		case *int16:
-			CheckBufferSize(data, 2)
-			*v = int16(defaultByteOrder.Uint16(data))
-			return 2
-		case *uint16:
-			CheckBufferSize(data, 2)
-			*v = defaultByteOrder.Uint16(data)
-			return 2
-		case *int:
-			CheckBufferSize(data, 4)
-			*v = int(int32(defaultByteOrder.Uint32(data)))
-			return 4
-		case *uint:
-			CheckBufferSize(data, 4)
-			*v = uint(defaultByteOrder.Uint32(data))
-			return 4
-		case *int32:
-			CheckBufferSize(data, 4)
-			*v = int32(defaultByteOrder.Uint32(data))
-			return 4
-		case *uint32:
-			CheckBufferSize(data, 4)
-			*v = defaultByteOrder.Uint32(data)
-			return 4
-		case *int64:
-			CheckBufferSize(data, 8)
-			*v = int64(defaultByteOrder.Uint64(data))
-			return 8
-		case *uint64:
-			CheckBufferSize(data, 8)
-			*v = defaultByteOrder.Uint64(data)
-			return 8
-		case *float32:
-			CheckBufferSize(data, 4)
-			*v = math.Float32frombits(defaultByteOrder.Uint32(data))
-			return 4
-		case *float64:
-			CheckBufferSize(data, 8)
-			*v = math.Float64frombits(defaultByteOrder.Uint64(data))
-			return 8
-		}
+	n, err := DecodeSimpleType(p, data)
+	if err != nil {
+		panic(NewUnmarshalObjectError(err))
 	}
-	panic(NewUnmarshalObjectError(errors.New("UnmarshalSimpleType: Unknown type")))
+	return n
+}
+
+// simpleTypeSize returns the encoded size of the type p points to, 0 for
+// unknown types
+func simpleTypeSize(p interface{}) int {
+	switch p.(type) {
+	case *byte, *int8, *bool:
+		return 1
+	case *int16, *uint16:
+		return 2
+	case *int, *uint, *int32, *uint32, *float32:
+		return 4
+	case *int64, *uint64, *float64, *time.Time, *time.Duration:
+		return 8
+	}
+	return 0
+}
+
+// DecodeSimpleType is like UnmarshalSimpleType but returns a
+// *ShortBufferError or an error wrapping ErrUnknownType instead of panic
+func DecodeSimpleType(p interface{}, data []byte) (int, error) {
+	return NewDecoder(defaultByteOrder).DecodeSimpleType(p, data)
 }
 
 func MarshalString(s string) []byte {
-	var buf bytes.Buffer
-	buf.Write(MarshalSimpleType(uint16(len(s))))
-	buf.WriteString(s)
-	return buf.Bytes()
+	return NewEncoder(defaultByteOrder).MarshalString(s)
+}
+
+// EncodeString is like MarshalString but returns ErrStringTooLong instead of
+// panic
+func EncodeString(s string) ([]byte, error) {
+	return NewEncoder(defaultByteOrder).EncodeString(s)
+}
+
+// AppendString is like EncodeString but appends to dst
+func AppendString(dst []byte, s string) ([]byte, error) {
+	return NewEncoder(defaultByteOrder).AppendString(dst, s)
 }
 
 func UnmarshalString(dest *string, data []byte) int {
-	CheckBufferSize(data, 2)
-	var len uint16
-	offset := UnmarshalSimpleType(&len, data)
-	CheckBufferSize(data, int(len), offset)
-	*dest = string(data[offset : offset+int(len)])
-	return offset + int(len)
+	n, err := DecodeString(dest, data)
+	if err != nil {
+		panic(NewUnmarshalObjectError(err))
+	}
+	return n
+}
+
+// DecodeString is like UnmarshalString but returns a *ShortBufferError
+// instead of panic
+func DecodeString(dest *string, data []byte) (int, error) {
+	return NewDecoder(defaultByteOrder).DecodeString(dest, data)
 }
 
 func MarshalObject(obj encoding.BinaryMarshaler) []byte {
-	binary, err := obj.MarshalBinary()
+	data, err := EncodeObject(obj)
 	if err != nil {
 		panic(NewMarshalObjectError(err))
 	}
-	var buf bytes.Buffer
-	buf.Write(binary)
-	return buf.Bytes()
+	return data
+}
+
+// EncodeObject is like MarshalObject but returns the error of MarshalBinary
+// instead of panic
+func EncodeObject(obj encoding.BinaryMarshaler) ([]byte, error) {
+	return obj.MarshalBinary()
+}
+
+// AppendObject is like EncodeObject but appends to dst
+func AppendObject(dst []byte, obj encoding.BinaryMarshaler) ([]byte, error) {
+	data, err := obj.MarshalBinary()
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, data...), nil
 }
 
 func UnmarshalObject(dest BinaryUnmarshalerWithSize, data []byte) int {
-	n, err := dest.UnmarshalBinaryWithSize(data)
+	n, err := DecodeObject(dest, data)
 	if err != nil {
 		panic(NewUnmarshalObjectError(err))
 	}
 	return n
 }
 
+// DecodeObject is like UnmarshalObject but returns the error instead of panic
+func DecodeObject(dest BinaryUnmarshalerWithSize, data []byte) (int, error) {
+	return dest.UnmarshalBinaryWithSize(data)
+}
+
 func MarshalProtoMessage(pb proto.Message) ([]byte, error) {
-	data, err := proto.Marshal(pb)
+	data, err := EncodeProtoMessage(pb)
 	if err != nil {
 		panic(NewMarshalObjectError(err))
 	}
-	result := PackLength(uint32(len(data)))
-	result.AppendBytes(data)
-	result.AddCrc16()
-	return []byte(result), nil
+	return data, nil
+}
+
+// EncodeProtoMessage is like MarshalProtoMessage but returns the error
+// instead of panic
+func EncodeProtoMessage(pb proto.Message) ([]byte, error) {
+	data, err := proto.Marshal(pb)
+	if err != nil {
+		return nil, err
+	}
+	return appendFrame(nil, data), nil
+}
+
+// protoAppender is implemented by the messages generated by protoc-gen-go,
+// it lets AppendProtoFrame marshal into dst without a temporary buffer
+type protoAppender interface {
+	XXX_Size() int
+	XXX_Marshal(b []byte, deterministic bool) ([]byte, error)
+}
+
+// AppendProtoFrame is like EncodeProtoMessage but appends the frame to dst,
+// for generated messages it does not allocate if dst has enough capacity
+func AppendProtoFrame(dst []byte, pb proto.Message) ([]byte, error) {
+	m, ok := pb.(protoAppender)
+	if !ok {
+		data, err := proto.Marshal(pb)
+		if err != nil {
+			return dst, err
+		}
+		return appendFrame(dst, data), nil
+	}
+	start := len(dst)
+	dst, err := LengthCodec{}.Append(dst, uint64(m.XXX_Size()))
+	if err != nil {
+		return dst[:start], err
+	}
+	dst, err = m.XXX_Marshal(dst, false)
+	if err != nil {
+		return dst[:start], err
+	}
+	return appendFrameCrc(dst, start), nil
 }
 
 func UnmarshalProtoMessage(data []byte, out proto.Message) int {
-	dataLength, err := UnpackLength(ByteArray(data))
+	n, err := DecodeProtoMessage(data, out)
 	if err != nil {
 		panic(NewUnmarshalObjectError(err))
 	}
-	lengthSize := ByteCountOfPackedLength(dataLength)
-	totalLength := lengthSize + int(dataLength) + 2
-	CheckBufferSize(data, totalLength)
-	if !algorithm.VerifyCrc16(data[:totalLength]) {
-		panic(NewUnmarshalObjectError(errors.New("CRC check fail")))
+	return n
+}
+
+// DecodeProtoMessage is like UnmarshalProtoMessage but returns the error
+// instead of panic, ErrCrcMismatch if the CRC is wrong
+func DecodeProtoMessage(data []byte, out proto.Message) (int, error) {
+	dataLength, lengthSize, err := UnpackLength(ByteArray(data))
+	if err != nil {
+		return 0, err
+	}
+	if uint64(dataLength) > uint64(len(data)) {
+		return 0, shortBufferOf(uint64(dataLength), data, lengthSize)
+	}
+	totalLength := lengthSize + int(dataLength) + protoMessageCrc.Size()
+	if err := CheckBuffer(data, totalLength, 0); err != nil {
+		return 0, err
+	}
+	if !protoMessageCrc.Verify(data[:totalLength]) {
+		return 0, ErrCrcMismatch
 	}
 	err = proto.Unmarshal(data[lengthSize:lengthSize+int(dataLength)], out)
 	if err != nil {
-		panic(NewUnmarshalObjectError(err))
+		return 0, err
 	}
-	return totalLength
+	return totalLength, nil
 }
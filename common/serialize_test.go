@@ -1,11 +1,16 @@
 package common_test
 
 import (
+	"encoding/binary"
 	"errors"
+	"github.com/newkedison/go-utils/algorithm"
 	"github.com/newkedison/go-utils/common"
+	"github.com/newkedison/go-utils/internal/types"
 	"github.com/stretchr/testify/assert"
 	"math"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestMarshalSimple(t *testing.T) {
@@ -183,6 +188,7 @@ func (myByteOrder) String() string { return "myByteOrder" }
 func TestSetByteOrder(t *testing.T) {
 	assert := assert.New(t)
 	common.SetByteOrder(myByteOrder{})
+	defer common.SetByteOrder(binary.LittleEndian)
 	assert.Equal(common.MarshalSimpleType(uint32(0)), []byte{0xAA, 0xAA, 0xAA, 0x00})
 	var v uint32
 	assert.EqualValues(common.UnmarshalSimpleType(&v, []byte{0xAA, 0xFF, 0xAA, 0xFF}), 4)
@@ -245,3 +251,400 @@ func TestMarshalProtoMessage(t *testing.T) {
 	assert := assert.New(t)
 	assert.Panics(func() { common.MarshalProtoMessage(nil) })
 }
+
+func TestProtoMessageCrcPinned(t *testing.T) {
+	assert := assert.New(t)
+	buf, err := common.NewNumber(1).MarshalBinary()
+	assert.Nil(err)
+	old := algorithm.DefaultCrc16ConfigIndex
+	algorithm.DefaultCrc16ConfigIndex = 0
+	defer func() { algorithm.DefaultCrc16ConfigIndex = old }()
+	again, err := common.NewNumber(1).MarshalBinary()
+	assert.Nil(err)
+	assert.Equal(again, buf)
+	var n common.Number
+	assert.Nil(n.UnmarshalBinary(buf))
+	assert.EqualValues(n, 1)
+}
+
+func TestEncodeDecodeSimpleType(t *testing.T) {
+	assert := assert.New(t)
+	data, err := common.EncodeSimpleType(uint16(0x1234))
+	assert.Nil(err)
+	assert.Equal(data, []byte{0x34, 0x12})
+	_, err = common.EncodeSimpleType("string")
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	assert.Equal(err.Error(), "MarshalSimpleType: Unknown type")
+	var u uint16
+	n, err := common.DecodeSimpleType(&u, data)
+	assert.Nil(err)
+	assert.Equal(n, 2)
+	assert.EqualValues(u, 0x1234)
+	_, err = common.DecodeSimpleType(u, data)
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	var f float64
+	_, err = common.DecodeSimpleType(&f, data)
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+	var shortBuffer *common.ShortBufferError
+	if assert.True(errors.As(err, &shortBuffer)) {
+		assert.Equal(*shortBuffer,
+			common.ShortBufferError{Required: 8, Offered: 2, Offset: 0})
+	}
+	assert.Equal(err.Error(), "Not enought data, require 8, offer 2-0=2")
+}
+
+func TestCheckBuffer(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(common.CheckBuffer([]byte{1, 2}, 1, 1))
+	err := common.CheckBuffer([]byte{1, 2}, 2, 1)
+	assert.Equal(err,
+		&common.ShortBufferError{Required: 2, Offered: 2, Offset: 1})
+	assert.NotNil(common.CheckBuffer(nil, -1, 0))
+	assert.NotNil(common.CheckBuffer([]byte{1, 2}, math.MaxInt64, 1))
+	assert.NotNil(common.CheckBuffer([]byte{1, 2}, 0, 3))
+	var e error
+	func() {
+		defer common.SetErrorWhenUnmarshalObjectErrorPanic("aaa", &e)()
+		common.CheckBufferSize([]byte{1, 2}, 2, 1)
+	}()
+	assert.Equal(e.Error(),
+		"Unmarshal aaa fail: Not enought data, require 2, offer 2-1=1")
+}
+
+func TestEncodeDecodeString(t *testing.T) {
+	assert := assert.New(t)
+	data, err := common.EncodeString("AAA")
+	assert.Nil(err)
+	assert.Equal(data, []byte{0x03, 0x00, 0x41, 0x41, 0x41})
+	_, err = common.EncodeString(string(make([]byte, 65536)))
+	assert.Equal(err, common.ErrStringTooLong)
+	var s string
+	n, err := common.DecodeString(&s, data)
+	assert.Nil(err)
+	assert.Equal(n, 5)
+	assert.Equal(s, "AAA")
+	_, err = common.DecodeString(&s, data[:4])
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+	_, err = common.DecodeString(&s, nil)
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+}
+
+func TestEncodeDecodeObject(t *testing.T) {
+	assert := assert.New(t)
+	data, err := common.EncodeObject(marshalableObject(1))
+	assert.Nil(err)
+	assert.Equal(data, []byte{0x01, 0x00, 0x00, 0x00})
+	_, err = common.EncodeObject(marshalableObject(0))
+	assert.NotNil(err)
+	var o marshalableObject
+	n, err := common.DecodeObject(&o, []byte{0x0A, 0x00, 0x00, 0x00})
+	assert.Nil(err)
+	assert.Equal(n, 4)
+	_, err = common.DecodeObject(&o, []byte{0x00, 0x00, 0x00, 0x00})
+	assert.NotNil(err)
+}
+
+func TestEncodeDecodeProtoMessage(t *testing.T) {
+	assert := assert.New(t)
+	_, err := common.EncodeProtoMessage(nil)
+	assert.NotNil(err)
+	data, err := common.EncodeProtoMessage(common.NewNumber(1).ToProtoMessage())
+	assert.Nil(err)
+	var pb types.WSNumber
+	n, err := common.DecodeProtoMessage(data, &pb)
+	assert.Nil(err)
+	assert.Equal(n, len(data))
+	assert.EqualValues(pb.Value, 1)
+	_, err = common.DecodeProtoMessage(data[:len(data)-1], &pb)
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+	data[len(data)-1] ^= 0x01
+	_, err = common.DecodeProtoMessage(data, &pb)
+	assert.Equal(err, common.ErrCrcMismatch)
+	_, err = common.DecodeProtoMessage(nil, &pb)
+	assert.Equal(err, common.InvalidPackedLengthError)
+}
+
+func TestAppendSimpleType(t *testing.T) {
+	assert := assert.New(t)
+	values := []interface{}{byte(1), int8(-1), int16(-2), uint16(3), int(-4),
+		uint(5), int32(-6), uint32(7), int64(-8), uint64(9), float32(1.5),
+		float64(-2.5)}
+	dst := []byte{0xAA}
+	var expected []byte
+	for _, v := range values {
+		var err error
+		dst, err = common.AppendSimpleType(dst, v)
+		assert.Nil(err)
+		expected = append(expected, common.MarshalSimpleType(v)...)
+	}
+	assert.Equal(dst, append([]byte{0xAA}, expected...))
+	_, err := common.AppendSimpleType(dst, "string")
+	assert.True(errors.Is(err, common.ErrUnknownType))
+}
+
+func TestAppendEncodedString(t *testing.T) {
+	assert := assert.New(t)
+	dst, err := common.AppendString([]byte{0xAA}, "AAA")
+	assert.Nil(err)
+	assert.Equal(dst, []byte{0xAA, 0x03, 0x00, 0x41, 0x41, 0x41})
+	dst, err = common.AppendString(dst[:1], string(make([]byte, 65536)))
+	assert.Equal(err, common.ErrStringTooLong)
+	assert.Equal(dst, []byte{0xAA})
+}
+
+func TestAppendObject(t *testing.T) {
+	assert := assert.New(t)
+	dst, err := common.AppendObject([]byte{0xAA}, marshalableObject(1))
+	assert.Nil(err)
+	assert.Equal(dst, []byte{0xAA, 0x01, 0x00, 0x00, 0x00})
+	_, err = common.AppendObject(dst, marshalableObject(0))
+	assert.NotNil(err)
+}
+
+func TestAppendProtoFrame(t *testing.T) {
+	assert := assert.New(t)
+	pb := common.NewNumber(1).ToProtoMessage()
+	frame, err := common.EncodeProtoMessage(pb)
+	assert.Nil(err)
+	dst, err := common.AppendProtoFrame([]byte{0xAA}, pb)
+	assert.Nil(err)
+	assert.Equal(dst, append([]byte{0xAA}, frame...))
+	dst, err = common.AppendProtoFrame(dst, pb)
+	assert.Nil(err)
+	var out types.WSNumber
+	n, err := common.DecodeProtoMessage(dst[1+len(frame):], &out)
+	assert.Nil(err)
+	assert.Equal(n, len(frame))
+	assert.EqualValues(out.Value, 1)
+}
+
+func TestAppendZeroAllocation(t *testing.T) {
+	assert := assert.New(t)
+	buf := make([]byte, 0, 256)
+	value := uint32(0x12345678)
+	pb := common.NewNumber(1).ToProtoMessage()
+	assert.Equal(testing.AllocsPerRun(100, func() {
+		dst, _ := common.AppendSimpleType(buf, value)
+		dst, _ = common.AppendSimpleType(dst, float64(value))
+		dst, _ = common.AppendString(dst, "telemetry")
+		common.AppendProtoFrame(dst, pb)
+	}), float64(0))
+}
+
+func BenchmarkMarshalSimpleType(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		common.MarshalSimpleType(uint32(i))
+	}
+}
+
+func BenchmarkAppendSimpleType(b *testing.B) {
+	b.ReportAllocs()
+	buf := make([]byte, 0, 8)
+	for i := 0; i < b.N; i++ {
+		common.AppendSimpleType(buf, uint32(i))
+	}
+}
+
+func BenchmarkMarshalString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		common.MarshalString("telemetry")
+	}
+}
+
+func BenchmarkAppendString(b *testing.B) {
+	b.ReportAllocs()
+	buf := make([]byte, 0, 64)
+	for i := 0; i < b.N; i++ {
+		common.AppendString(buf, "telemetry")
+	}
+}
+
+func BenchmarkMarshalProtoMessage(b *testing.B) {
+	b.ReportAllocs()
+	pb := common.NewNumber(1).ToProtoMessage()
+	for i := 0; i < b.N; i++ {
+		common.MarshalProtoMessage(pb)
+	}
+}
+
+func BenchmarkAppendProtoFrame(b *testing.B) {
+	b.ReportAllocs()
+	pb := common.NewNumber(1).ToProtoMessage()
+	buf := make([]byte, 0, 64)
+	for i := 0; i < b.N; i++ {
+		common.AppendProtoFrame(buf, pb)
+	}
+}
+
+func TestMarshalSimpleTypeBoolAndTime(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(common.MarshalSimpleType(true), []byte{0x01})
+	assert.Equal(common.MarshalSimpleType(false), []byte{0x00})
+	tm := time.Unix(1500000000, 123456789)
+	data := common.MarshalSimpleType(tm)
+	assert.Equal(data,
+		common.MarshalSimpleType((&common.AlarmRecord{Time: tm}).ToProtoMessage().Time))
+	assert.Equal(common.MarshalSimpleType(1500*time.Millisecond),
+		common.MarshalSimpleType(int64(1500)))
+
+	var b bool
+	assert.Equal(common.UnmarshalSimpleType(&b, []byte{0x02}), 1)
+	assert.True(b)
+	var result time.Time
+	assert.Equal(common.UnmarshalSimpleType(&result, data), 8)
+	assert.True(result.Equal(time.Unix(1500000000, 123000000)))
+	var d time.Duration
+	common.UnmarshalSimpleType(&d, common.MarshalSimpleType(int64(-1500)))
+	assert.Equal(d, -1500*time.Millisecond)
+	assert.Panics(func() { common.UnmarshalSimpleType(&d, data[:7]) })
+}
+
+func TestMarshalSimpleTypeComposite(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(common.MarshalSimpleType([]uint16{1, 2}),
+		[]byte{0x02, 0x01, 0x00, 0x02, 0x00})
+	assert.Equal(common.MarshalSimpleType([]byte{1, 2}), []byte{0x02, 0x01, 0x02})
+	assert.Equal(common.MarshalSimpleType([2]int8{-1, 1}), []byte{0xFF, 0x01})
+	assert.Equal(common.MarshalSimpleType(map[uint8]bool{2: false, 1: true}),
+		[]byte{0x02, 0x01, 0x01, 0x02, 0x00})
+	long := make([]byte, 0x80)
+	assert.Equal(common.MarshalSimpleType(long),
+		append([]byte{0x80, 0x80}, long...))
+	assert.Equal(common.MarshalSimpleType([]uint16{}), []byte{0x00})
+
+	values := []interface{}{
+		[]uint16{1, 2},
+		[]byte{1, 2, 3},
+		[2]int8{-1, 1},
+		[][]float32{{1.5}, {}, {-2, 3}},
+		map[int32][]bool{-1: {true}, 7: {false, true}},
+		map[string]int(nil),
+		[]time.Time{time.Unix(1, 0), time.Unix(2, 5000000)},
+		[3]time.Duration{time.Second, 0, -time.Millisecond},
+	}
+	for _, v := range values[:5] {
+		data := common.MarshalSimpleType(v)
+		p := reflect.New(reflect.TypeOf(v))
+		n := common.UnmarshalSimpleType(p.Interface(), append(data, 0xFF))
+		assert.Equal(n, len(data))
+		assert.Equal(p.Elem().Interface(), v)
+	}
+	_, err := common.EncodeSimpleType(values[5])
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	var times []time.Time
+	common.UnmarshalSimpleType(&times, common.MarshalSimpleType(values[6]))
+	if assert.Len(times, 2) {
+		assert.True(times[0].Equal(time.Unix(1, 0)))
+		assert.True(times[1].Equal(time.Unix(2, 5000000)))
+	}
+	var durations [3]time.Duration
+	common.UnmarshalSimpleType(&durations, common.MarshalSimpleType(values[7]))
+	assert.Equal(durations, values[7])
+}
+
+func TestAppendComposite(t *testing.T) {
+	assert := assert.New(t)
+	dst, err := common.AppendComposite([]byte{0xAA}, []int16{-1})
+	assert.Nil(err)
+	assert.Equal(dst, []byte{0xAA, 0x01, 0xFF, 0xFF})
+	dst, err = common.AppendComposite(dst[:1], uint16(1))
+	assert.Nil(err)
+	assert.Equal(dst, []byte{0xAA, 0x01, 0x00})
+	_, err = common.AppendSimpleType(dst, []int16{-1})
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	_, err = common.AppendComposite(dst, []string{"a"})
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	_, err = common.AppendComposite(dst, nil)
+	assert.True(errors.Is(err, common.ErrUnknownType))
+}
+
+func TestUnmarshalSimpleTypeCompositeError(t *testing.T) {
+	assert := assert.New(t)
+	var s []uint32
+	_, err := common.DecodeSimpleType(&s, []byte{0x02, 0x01, 0x00, 0x00, 0x00,
+		0x02})
+	assert.Equal(err,
+		&common.ShortBufferError{Required: 4, Offered: 6, Offset: 5})
+	_, err = common.DecodeSimpleType(&s, []byte{0xF0, 0xFF, 0xFF, 0xFF, 0xFF})
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+	_, err = common.DecodeSimpleType(&s, []byte{0xFF})
+	assert.Equal(err, common.InvalidPackedLengthError)
+	var m map[uint8]uint8
+	_, err = common.DecodeSimpleType(&m, []byte{0x01, 0x01})
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+	var strs []string
+	_, err = common.DecodeSimpleType(&strs, []byte{0x00})
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	_, err = common.DecodeSimpleType(s, []byte{0x00})
+	assert.True(errors.Is(err, common.ErrUnknownType))
+	_, err = common.DecodeSimpleType((*[]uint32)(nil), []byte{0x00})
+	assert.True(errors.Is(err, common.ErrUnknownType))
+}
+
+func TestDecodeProtoMessageHugeLength(t *testing.T) {
+	assert := assert.New(t)
+	var pb types.WSNumber
+	_, err := common.DecodeProtoMessage([]byte{0xF0, 0xFF, 0xFF, 0xFF, 0xFF,
+		0x00}, &pb)
+	assert.Equal(err, &common.ShortBufferError{Required: 0xFFFFFFFF,
+		Offered: 6, Offset: 5})
+	var s []uint16
+	_, err = common.DecodeSimpleType(&s, []byte{0xF0, 0xFF, 0xFF, 0xFF, 0xFF})
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+}
+
+// checkDecoded fails t if a decoder consumed more than the offered bytes
+func checkDecoded(t *testing.T, n int, err error, data []byte) {
+	if err == nil && (n < 0 || n > len(data)) {
+		t.Fatalf("consumed %d of %d bytes", n, len(data))
+	}
+}
+
+func FuzzDecodeProtoMessage(f *testing.F) {
+	data, _ := common.EncodeProtoMessage(common.NewNumber(1).ToProtoMessage())
+	f.Add(data)
+	f.Add([]byte{0x00, 0x01, 0xC0})
+	f.Add([]byte{0xF0, 0xFF, 0xFF, 0xFF, 0xFF})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var pb types.WSNumber
+		n, err := common.DecodeProtoMessage(data, &pb)
+		checkDecoded(t, n, err, data)
+	})
+}
+
+func FuzzDecodeSimpleType(f *testing.F) {
+	f.Add(common.MarshalSimpleType([]uint16{1, 2}))
+	f.Add(common.MarshalSimpleType(map[uint8][]int32{1: {-1}, 2: nil}))
+	f.Add(common.MarshalSimpleType([][]byte{{1, 2}, {}}))
+	f.Add([]byte{0xF0, 0xFF, 0xFF, 0xFF, 0xFF})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var (
+			i  int32
+			d  float64
+			tm time.Time
+			s  []uint16
+			a  [2]float32
+			m  map[uint8][]int32
+			b  [][]byte
+		)
+		for _, p := range []interface{}{&i, &d, &tm, &s, &a, &m, &b} {
+			n, err := common.DecodeSimpleType(p, data)
+			checkDecoded(t, n, err, data)
+		}
+	})
+}
+
+func FuzzDecodeString(f *testing.F) {
+	f.Add(common.MarshalString("AB"))
+	f.Add([]byte{0xFF, 0xFF, 0x41})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var s string
+		n, err := common.DecodeString(&s, data)
+		checkDecoded(t, n, err, data)
+		if err == nil && n != len(s)+2 {
+			t.Fatalf("decoded %q from %d bytes", s, n)
+		}
+	})
+}
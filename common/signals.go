@@ -1,51 +1,371 @@
 package common
 
-type SignalAlarm []func(Number, *Alarm)
-type SignalDataCheck []func(*NamedData, Number) bool
-type SignalDataModified []func(*NamedData, Number, Number)
-type SignalDataRangeModified []func(Range, Range)
+import (
+	"context"
+	"sort"
+	"sync"
+)
 
-func (sig *SignalAlarm) Connect(f func(Number, *Alarm)) {
-	(*sig) = append(*sig, f)
+// slot is a callback connected to a signal
+type slot struct {
+	conn     *Connection
+	priority int
+	once     bool
+	f        interface{}
 }
 
-func (sig *SignalDataCheck) Connect(f func(*NamedData, Number) bool) {
-	(*sig) = append(*sig, f)
+// slotList holds the callbacks of a signal, sorted by decreasing priority
+// then connection order. It is shared by the copies of the signal, so a
+// Connection can remove its callback from any goroutine.
+type slotList struct {
+	mu    sync.Mutex
+	slots []slot
+	// async is the dispatcher of an asynchronous signal
+	async *dispatcher
+	// stats are the counters of the previous dispatchers
+	stats DispatchStats
 }
 
-func (sig *SignalDataModified) Connect(
-	f func(*NamedData, Number, Number)) {
-	(*sig) = append(*sig, f)
+// signal is embedded in the signal types, its zero value has no callback
+// and all the methods can be called from any goroutine. A copy of a signal
+// shares its callbacks once one has been connected.
+type signal struct {
+	list *slotList
 }
 
-func (sig *SignalDataRangeModified) Connect(f func(Range, Range)) {
-	(*sig) = append(*sig, f)
+// signalMu guards the list of the signals, which is allocated by the first
+// Connect or SetAsync
+var signalMu sync.Mutex
+
+// slots returns the callbacks of sig, they are allocated if create is true,
+// otherwise nil is returned for a signal without callback
+func (sig *signal) slots(create bool) *slotList {
+	signalMu.Lock()
+	defer signalMu.Unlock()
+	if sig.list == nil && create {
+		sig.list = &slotList{}
+	}
+	return sig.list
 }
 
-func (sig *SignalAlarm) fire(value Number, alarm *Alarm) {
-	for _, f := range *sig {
-		f(value, alarm)
+// ConnectOption changes how a callback is connected
+type ConnectOption func(*connectConfig)
+
+type connectConfig struct {
+	priority int
+	ctx      context.Context
+}
+
+// WithPriority calls the callback before the ones with a lower priority,
+// callbacks with the same priority are called in connection order. The
+// default priority is 0.
+func WithPriority(priority int) ConnectOption {
+	return func(c *connectConfig) {
+		c.priority = priority
 	}
 }
 
-func (sig *SignalDataCheck) fire(data *NamedData, setValue Number) bool {
-	for _, f := range *sig {
-		if !f(data, setValue) {
-			return false
+// WithContext disconnects the callback when ctx is done
+func WithContext(ctx context.Context) ConnectOption {
+	return func(c *connectConfig) {
+		c.ctx = ctx
+	}
+}
+
+// Connection is the handle of a connected callback
+type Connection struct {
+	list *slotList
+	once sync.Once
+	// done is closed on disconnection to stop the goroutine of WithContext
+	done chan struct{}
+}
+
+// Disconnect removes the callback from the signal, it can be called more
+// than once and from any goroutine. A callback being called by another
+// goroutine may still finish after Disconnect returns.
+func (c *Connection) Disconnect() {
+	c.disconnect()
+}
+
+// disconnect returns true for the call which removed the callback
+func (c *Connection) disconnect() (removed bool) {
+	c.once.Do(func() {
+		removed = c.list.remove(c)
+		if c.done != nil {
+			close(c.done)
 		}
+	})
+	return removed
+}
+
+// Connected returns false after Disconnect, after a ConnectOnce callback is
+// called or after the context of WithContext is done
+func (c *Connection) Connected() bool {
+	return c.list.has(c)
+}
+
+func (sig *signal) connect(f interface{}, once bool,
+	opts []ConnectOption) *Connection {
+	var config connectConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	l := sig.slots(true)
+	c := &Connection{list: l}
+	if config.ctx != nil {
+		c.done = make(chan struct{})
+	}
+	l.add(slot{c, config.priority, once, f})
+	if config.ctx != nil {
+		go func() {
+			select {
+			case <-config.ctx.Done():
+				c.Disconnect()
+			case <-c.done:
+			}
+		}()
+	}
+	return c
+}
+
+// Len returns the number of connected callbacks
+func (sig *signal) Len() int {
+	return len(sig.callbacks())
+}
+
+// DisconnectAll removes all the callbacks
+func (sig *signal) DisconnectAll() {
+	for _, s := range sig.callbacks() {
+		s.conn.Disconnect()
+	}
+}
+
+// callbacks returns the callbacks to call when the signal fires, a once
+// callback is only called if take returns true. Callbacks connected or
+// disconnected while the signal fires take effect at the next fire.
+func (sig *signal) callbacks() []slot {
+	l := sig.slots(false)
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.slots
+}
+
+// take returns whether s is to be called, a once callback is disconnected
+// and only taken by the first call
+func (sig *signal) take(s slot) bool {
+	return !s.once || s.conn.disconnect()
+}
+
+func (l *slotList) add(s slot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := sort.Search(len(l.slots), func(i int) bool {
+		return l.slots[i].priority < s.priority
+	})
+	// slots is copied, so the callbacks returned before are not changed
+	slots := make([]slot, 0, len(l.slots)+1)
+	slots = append(slots, l.slots[:i]...)
+	slots = append(slots, s)
+	l.slots = append(slots, l.slots[i:]...)
+}
+
+// remove removes the callback of c, it returns false if it is not connected
+func (l *slotList) remove(c *Connection) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, s := range l.slots {
+		if s.conn == c {
+			slots := make([]slot, 0, len(l.slots)-1)
+			slots = append(slots, l.slots[:i]...)
+			l.slots = append(slots, l.slots[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (l *slotList) has(c *Connection) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, s := range l.slots {
+		if s.conn == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Disconnect removes the callback of c if it is connected to sig
+func (sig *signal) Disconnect(c *Connection) {
+	if c.list == sig.slots(false) {
+		c.Disconnect()
+	}
+}
+
+// Signal calls the connected callbacks with the argument of Fire, a struct
+// when there is more than one value to pass
+type Signal[T any] struct{ signal }
+
+func (sig *Signal[T]) Connect(f func(T), opts ...ConnectOption) *Connection {
+	return sig.connect(f, false, opts)
+}
+
+// ConnectOnce connects f to be called only the next time the signal fires
+func (sig *Signal[T]) ConnectOnce(f func(T),
+	opts ...ConnectOption) *Connection {
+	return sig.connect(f, true, opts)
+}
+
+// Fire calls the callbacks with arg in the calling goroutine, or queues the
+// call if the signal is asynchronous
+func (sig *Signal[T]) Fire(arg T) {
+	slots, d := sig.dispatch()
+	if d == nil {
+		sig.deliver(slots, arg, nil)
+		return
+	}
+	if !d.enqueue(func() { sig.deliver(slots, arg, d) }) {
+		sig.deliver(slots, arg, nil)
 	}
-	return true
 }
 
-func (sig *SignalDataModified) fire(data *NamedData, oldValue Number,
-	newValue Number) {
-	for _, f := range *sig {
-		f(data, oldValue, newValue)
+// deliver calls the callbacks of slots, d recovers their panics
+func (sig *Signal[T]) deliver(slots []slot, arg T, d *dispatcher) {
+	for _, s := range slots {
+		if !sig.take(s) {
+			continue
+		}
+		f := s.f.(func(T))
+		if d == nil {
+			f(arg)
+		} else {
+			d.call(func() { f(arg) })
+		}
 	}
 }
 
-func (sig *SignalDataRangeModified) fire(oldRange Range, newRange Range) {
-	for _, f := range *sig {
-		f(oldRange, newRange)
+// BoolSignal is a Signal which callbacks can veto, such as to refuse a value
+type BoolSignal[T any] struct{ signal }
+
+func (sig *BoolSignal[T]) Connect(f func(T) bool,
+	opts ...ConnectOption) *Connection {
+	return sig.connect(f, false, opts)
+}
+
+// ConnectOnce connects f to be called only the next time the signal fires
+func (sig *BoolSignal[T]) ConnectOnce(f func(T) bool,
+	opts ...ConnectOption) *Connection {
+	return sig.connect(f, true, opts)
+}
+
+// Fire calls the callbacks with arg until one returns false, it returns
+// whether all callbacks returned true
+func (sig *BoolSignal[T]) Fire(arg T) bool {
+	for _, s := range sig.callbacks() {
+		if sig.take(s) && !s.f.(func(T) bool)(arg) {
+			return false
+		}
 	}
+	return true
+}
+
+type AlarmArgs struct {
+	Value Number
+	Alarm *Alarm
+}
+
+type DataCheckArgs struct {
+	Data  *NamedData
+	Value Number
+}
+
+type DataModifiedArgs struct {
+	Data     *NamedData
+	OldValue Number
+	NewValue Number
+}
+
+type RangeModifiedArgs struct {
+	OldRange Range
+	NewRange Range
+}
+
+// SignalAlarm is a Signal which Connect takes the arguments separately
+type SignalAlarm struct{ Signal[AlarmArgs] }
+
+// SignalDataCheck is a BoolSignal which Connect takes the arguments
+// separately
+type SignalDataCheck struct{ BoolSignal[DataCheckArgs] }
+
+// SignalDataModified is a Signal which Connect takes the arguments
+// separately
+type SignalDataModified struct{ Signal[DataModifiedArgs] }
+
+// SignalDataRangeModified is a Signal which Connect takes the arguments
+// separately
+type SignalDataRangeModified struct{ Signal[RangeModifiedArgs] }
+
+func alarmCallback(f func(Number, *Alarm)) func(AlarmArgs) {
+	return func(a AlarmArgs) { f(a.Value, a.Alarm) }
+}
+
+func dataCheckCallback(
+	f func(*NamedData, Number) bool) func(DataCheckArgs) bool {
+	return func(a DataCheckArgs) bool { return f(a.Data, a.Value) }
+}
+
+func dataModifiedCallback(
+	f func(*NamedData, Number, Number)) func(DataModifiedArgs) {
+	return func(a DataModifiedArgs) { f(a.Data, a.OldValue, a.NewValue) }
+}
+
+func rangeModifiedCallback(f func(Range, Range)) func(RangeModifiedArgs) {
+	return func(a RangeModifiedArgs) { f(a.OldRange, a.NewRange) }
+}
+
+func (sig *SignalAlarm) Connect(f func(Number, *Alarm),
+	opts ...ConnectOption) *Connection {
+	return sig.Signal.Connect(alarmCallback(f), opts...)
+}
+
+// ConnectOnce connects f to be called only the next time the signal fires
+func (sig *SignalAlarm) ConnectOnce(f func(Number, *Alarm),
+	opts ...ConnectOption) *Connection {
+	return sig.Signal.ConnectOnce(alarmCallback(f), opts...)
+}
+
+func (sig *SignalDataCheck) Connect(f func(*NamedData, Number) bool,
+	opts ...ConnectOption) *Connection {
+	return sig.BoolSignal.Connect(dataCheckCallback(f), opts...)
+}
+
+// ConnectOnce connects f to be called only the next time the signal fires
+func (sig *SignalDataCheck) ConnectOnce(f func(*NamedData, Number) bool,
+	opts ...ConnectOption) *Connection {
+	return sig.BoolSignal.ConnectOnce(dataCheckCallback(f), opts...)
+}
+
+func (sig *SignalDataModified) Connect(
+	f func(*NamedData, Number, Number), opts ...ConnectOption) *Connection {
+	return sig.Signal.Connect(dataModifiedCallback(f), opts...)
+}
+
+// ConnectOnce connects f to be called only the next time the signal fires
+func (sig *SignalDataModified) ConnectOnce(
+	f func(*NamedData, Number, Number), opts ...ConnectOption) *Connection {
+	return sig.Signal.ConnectOnce(dataModifiedCallback(f), opts...)
+}
+
+func (sig *SignalDataRangeModified) Connect(f func(Range, Range),
+	opts ...ConnectOption) *Connection {
+	return sig.Signal.Connect(rangeModifiedCallback(f), opts...)
+}
+
+// ConnectOnce connects f to be called only the next time the signal fires
+func (sig *SignalDataRangeModified) ConnectOnce(f func(Range, Range),
+	opts ...ConnectOption) *Connection {
+	return sig.Signal.ConnectOnce(rangeModifiedCallback(f), opts...)
 }
@@ -0,0 +1,341 @@
+package common_test
+
+import (
+	"context"
+	"github.com/newkedison/go-utils/common"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitDisconnected waits for the goroutine of WithContext
+func waitDisconnected(c *common.Connection) bool {
+	for i := 0; i < 1000 && c.Connected(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	return !c.Connected()
+}
+
+func TestSignalDisconnect(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewNamedData("id", "name", 0, common.NewRange(0, 100))
+	var calls []string
+	c1 := d.OnModified(func(*common.NamedData, common.Number, common.Number) {
+		calls = append(calls, "a")
+	})
+	c2 := d.OnModified(func(*common.NamedData, common.Number, common.Number) {
+		calls = append(calls, "b")
+	})
+	d.SetValue(1)
+	assert.Equal(calls, []string{"a", "b"})
+	assert.True(c1.Connected())
+	c1.Disconnect()
+	c1.Disconnect()
+	assert.False(c1.Connected())
+	assert.True(c2.Connected())
+	calls = nil
+	d.SetValue(2)
+	assert.Equal(calls, []string{"b"})
+
+	c := d.AddCheckWriteMethod(func(*common.NamedData, common.Number) bool {
+		return false
+	})
+	assert.False(d.SetValue(3))
+	c.Disconnect()
+	assert.True(d.SetValue(3))
+}
+
+func TestSignalConnectOnce(t *testing.T) {
+	assert := assert.New(t)
+	var sig common.SignalDataRangeModified
+	count := 0
+	c := sig.ConnectOnce(func(common.Range, common.Range) { count++ })
+	sig.Connect(func(common.Range, common.Range) { count += 10 })
+	assert.Equal(sig.Len(), 2)
+	d := common.NewNamedData("id", "name", 50, common.NewRange(0, 100))
+	d.Warning().SetRange(common.NewRange(0, 10)).Enable()
+	d.Warning().SignalAlarm().ConnectOnce(func(common.Number, *common.Alarm) {
+		count++
+	})
+	for _, v := range []common.Number{5, 50, 20} {
+		d.SetValue(v)
+		d.Warning().Check()
+	}
+	assert.Equal(count, 1)
+	assert.Equal(d.Warning().SignalAlarm().Len(), 0)
+	assert.True(c.Connected())
+}
+
+func TestSignalConnectOnceNotReached(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewNamedData("id", "name", 0, common.NewRange(0, 100))
+	count := 0
+	d.AddCheckWriteMethod(func(_ *common.NamedData, v common.Number) bool {
+		return v != 5
+	}, common.WithPriority(1))
+	// the copy of the signal shares the callbacks of d
+	sig := d.SignalCheckWrite()
+	c := sig.ConnectOnce(func(*common.NamedData, common.Number) bool {
+		count++
+		return true
+	})
+	// the first callback stops the signal, so the once callback is kept
+	assert.False(d.SetValue(5))
+	assert.Equal(count, 0)
+	assert.True(d.SetValue(6))
+	assert.True(d.SetValue(7))
+	assert.Equal(count, 1)
+	assert.False(c.Connected())
+}
+
+func TestSignalConnectOnceConcurrent(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 0, common.NewRange(0, 100))
+	var mu sync.Mutex
+	count := 0
+	c := d.OnModified(func(*common.NamedData, common.Number, common.Number) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+	})
+	var wg sync.WaitGroup
+	calls := 0
+	d.Update(func(data *common.NamedData) {
+		data.Warning().SetRange(common.NewRange(-1, 0)).Enable()
+		data.Warning().SignalAlarm().ConnectOnce(func(common.Number,
+			*common.Alarm) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+		})
+		data.SetAutoCheck(true)
+	})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			d.SetValue(common.Number(i + 1))
+			d.SetValue(0)
+		}(i)
+	}
+	wg.Wait()
+	c.Disconnect()
+	assert.Equal(count, 16)
+	assert.Equal(calls, 1)
+}
+
+func TestSignalConnectFireConcurrent(t *testing.T) {
+	assert := assert.New(t)
+	// the callbacks of a fresh signal are allocated while it fires
+	var sig common.Signal[int]
+	var mu sync.Mutex
+	sum := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sig.Connect(func(v int) {
+				mu.Lock()
+				sum += v
+				mu.Unlock()
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			sig.Fire(0)
+			sig.Len()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(sig.Len(), 8)
+	sig.Fire(1)
+	assert.Equal(sum, 8)
+
+	var async common.Signal[int]
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		async.SetAsync(common.AsyncConfig{})
+	}()
+	go func() {
+		defer wg.Done()
+		async.Connect(func(int) {})
+		async.Fire(0)
+	}()
+	wg.Wait()
+	async.StopAsync()
+	assert.EqualValues(async.DispatchStats().Dropped, 0)
+}
+
+func TestSignalPriority(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewNamedData("id", "name", 0, common.NewRange(0, 100))
+	var calls []int
+	for _, p := range []int{0, 10, -5, 10, 0} {
+		p := p
+		d.OnModified(func(*common.NamedData, common.Number, common.Number) {
+			calls = append(calls, p)
+		}, common.WithPriority(p))
+	}
+	d.SetValue(1)
+	assert.Equal(calls, []int{10, 10, 0, 0, -5})
+}
+
+func TestSignalWithContext(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 0, common.NewRange(0, 100))
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	count := 0
+	c := d.OnModified(func(*common.NamedData, common.Number, common.Number) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+	}, common.WithContext(ctx))
+	d.SetValue(1)
+	cancel()
+	assert.True(waitDisconnected(c))
+	d.SetValue(2)
+	mu.Lock()
+	assert.Equal(count, 1)
+	mu.Unlock()
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	c = d.OnRangeModified(func(common.Range, common.Range) {},
+		common.WithContext(ctx))
+	assert.True(waitDisconnected(c))
+
+	c = d.OnModified(func(*common.NamedData, common.Number, common.Number) {},
+		common.WithContext(context.Background()))
+	c.Disconnect()
+	assert.False(c.Connected())
+}
+
+func TestSignalDisconnectAll(t *testing.T) {
+	assert := assert.New(t)
+	var sig common.SignalDataModified
+	assert.Equal(sig.Len(), 0)
+	sig.DisconnectAll()
+	c := sig.Connect(func(*common.NamedData, common.Number, common.Number) {})
+	sig.Connect(func(*common.NamedData, common.Number, common.Number) {},
+		common.WithContext(context.Background()))
+	assert.Equal(sig.Len(), 2)
+	sig.DisconnectAll()
+	assert.Equal(sig.Len(), 0)
+	assert.False(c.Connected())
+}
+
+func TestSignalDisconnectWhileFiring(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewSafeNamedData("id", "name", 0, common.NewRange(0, 100))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				d.SetValue(common.Number(j))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				c := d.OnModified(func(*common.NamedData, common.Number,
+					common.Number) {
+				})
+				c.Disconnect()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.True(d.Value() < 50)
+}
+
+func TestSignalGeneric(t *testing.T) {
+	assert := assert.New(t)
+	var sig common.Signal[string]
+	var calls []string
+	c := sig.Connect(func(s string) { calls = append(calls, "a"+s) })
+	sig.ConnectOnce(func(s string) { calls = append(calls, "b"+s) },
+		common.WithPriority(1))
+	assert.Equal(sig.Len(), 2)
+	sig.Fire("1")
+	sig.Fire("2")
+	assert.Equal(calls, []string{"b1", "a1", "a2"})
+	sig.Disconnect(c)
+	assert.Equal(sig.Len(), 0)
+	calls = nil
+	sig.Fire("3")
+	assert.Nil(calls)
+
+	// a connection of another signal is not disconnected
+	var other common.Signal[string]
+	c = other.Connect(func(string) {})
+	sig.Disconnect(c)
+	assert.True(c.Connected())
+}
+
+func TestBoolSignal(t *testing.T) {
+	assert := assert.New(t)
+	var sig common.BoolSignal[int]
+	assert.True(sig.Fire(1))
+	count := 0
+	sig.Connect(func(v int) bool { return v > 0 })
+	sig.ConnectOnce(func(v int) bool {
+		count++
+		return true
+	})
+	assert.False(sig.Fire(-1))
+	assert.True(sig.Fire(1))
+	assert.True(sig.Fire(2))
+	assert.Equal(count, 1)
+	sig.DisconnectAll()
+	assert.True(sig.Fire(-1))
+}
+
+func TestSignalArgs(t *testing.T) {
+	assert := assert.New(t)
+	d := common.NewNamedData("id", "name", 0, common.NewRange(0, 100))
+	var args []common.DataModifiedArgs
+	var sig common.SignalDataModified
+	sig.Signal.Connect(func(a common.DataModifiedArgs) {
+		args = append(args, a)
+	})
+	sig.Connect(func(data *common.NamedData,
+		oldValue, newValue common.Number) {
+		args = append(args, common.DataModifiedArgs{Data: data,
+			OldValue: oldValue, NewValue: newValue})
+	})
+	sig.Fire(common.DataModifiedArgs{Data: &d, OldValue: 1, NewValue: 2})
+	assert.Equal(args, []common.DataModifiedArgs{
+		{Data: &d, OldValue: 1, NewValue: 2},
+		{Data: &d, OldValue: 1, NewValue: 2}})
+
+	var check common.SignalDataCheck
+	check.Connect(func(_ *common.NamedData, v common.Number) bool {
+		return v != 5
+	})
+	assert.False(check.Fire(common.DataCheckArgs{Data: &d, Value: 5}))
+	assert.True(check.Fire(common.DataCheckArgs{Data: &d, Value: 6}))
+
+	var ranges common.SignalDataRangeModified
+	var got []common.Range
+	ranges.Connect(func(oldRange, newRange common.Range) {
+		got = append(got, oldRange, newRange)
+	})
+	ranges.Fire(common.RangeModifiedArgs{OldRange: common.NewRange(0, 1),
+		NewRange: common.NewRange(0, 2)})
+	assert.Equal(got, []common.Range{common.NewRange(0, 1),
+		common.NewRange(0, 2)})
+
+	var alarm common.SignalAlarm
+	var values []common.Number
+	alarm.Connect(func(v common.Number, a *common.Alarm) {
+		values = append(values, v)
+	})
+	alarm.Fire(common.AlarmArgs{Value: 3, Alarm: d.Warning()})
+	assert.Equal(values, []common.Number{3})
+}
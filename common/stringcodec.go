@@ -0,0 +1,365 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// StringFormat is how the size of a string is encoded
+type StringFormat int
+
+const (
+	// StringU16 is a uint16 length prefix, the format of MarshalString
+	StringU16 StringFormat = iota
+	StringU8
+	StringU32
+	// StringPacked is a PackedLength prefix
+	StringPacked
+	// StringNulTerminated ends the string with a 0 byte
+	StringNulTerminated
+	// StringFixed always takes Width bytes, padded with Pad
+	StringFixed
+)
+
+// ErrInvalidString is wrapped by the errors of a string which can not be
+// encoded or decoded, such as invalid UTF-8 or a missing NUL terminator
+var ErrInvalidString = errors.New("Invalid string")
+
+// Charset converts between UTF-8 strings and a legacy encoding
+type Charset interface {
+	Name() string
+	// Encode appends the encoded s to dst
+	Encode(dst []byte, s string) ([]byte, error)
+	Decode(data []byte) (string, error)
+}
+
+// StringCodec encodes strings in the format used by a device. The zero value
+// is the format of MarshalString with a little-endian prefix.
+type StringCodec struct {
+	Format StringFormat
+	// Order is the byte order of the length prefix, little-endian if nil
+	Order binary.ByteOrder
+	// Width is the size of a StringFixed string
+	Width int
+	// Pad fills a StringFixed string, it is removed from the end when
+	// decoding. With the default 0 the string also ends at the first 0 byte.
+	Pad byte
+	// ValidateUTF8 rejects strings which are not valid UTF-8
+	ValidateUTF8 bool
+	// Charset is the encoding of the bytes on the wire, nil to copy the bytes
+	// of the string as they are
+	Charset Charset
+}
+
+func (c StringCodec) order() binary.ByteOrder {
+	if c.Order == nil {
+		return binary.LittleEndian
+	}
+	return c.Order
+}
+
+// Encode returns the encoded s, ErrStringTooLong is returned if s does not
+// fit in the prefix or the width
+func (c StringCodec) Encode(s string) ([]byte, error) {
+	return c.Append(nil, s)
+}
+
+// Append is like Encode but appends to dst, it does not allocate if dst has
+// enough capacity and Charset is nil
+func (c StringCodec) Append(dst []byte, s string) ([]byte, error) {
+	if c.ValidateUTF8 && !utf8.ValidString(s) {
+		return dst, fmt.Errorf("%w: invalid UTF-8", ErrInvalidString)
+	}
+	var encoded []byte
+	size := len(s)
+	if c.Charset != nil {
+		var err error
+		if encoded, err = c.Charset.Encode(nil, s); err != nil {
+			return dst, err
+		}
+		size = len(encoded)
+	}
+	start := len(dst)
+	var b []byte
+	switch c.Format {
+	case StringU8:
+		if size > math.MaxUint8 {
+			return dst, ErrStringTooLong
+		}
+		dst = append(dst, byte(size))
+	case StringU16:
+		if size > math.MaxUint16 {
+			return dst, ErrStringTooLong
+		}
+		dst, b = grow(dst, 2)
+		c.order().PutUint16(b, uint16(size))
+	case StringU32:
+		if uint64(size) > math.MaxUint32 {
+			return dst, ErrStringTooLong
+		}
+		dst, b = grow(dst, 4)
+		c.order().PutUint32(b, uint32(size))
+	case StringPacked:
+		var err error
+		if dst, err = (LengthCodec{}).Append(dst, uint64(size)); err != nil {
+			return dst, ErrStringTooLong
+		}
+	case StringNulTerminated:
+		if strings.IndexByte(s, 0) >= 0 || bytes.IndexByte(encoded, 0) >= 0 {
+			return dst, fmt.Errorf("%w: NUL in NUL-terminated string",
+				ErrInvalidString)
+		}
+	case StringFixed:
+		if size > c.Width {
+			return dst, ErrStringTooLong
+		}
+	default:
+		return dst, fmt.Errorf("%w: unknown format %d", ErrInvalidString,
+			c.Format)
+	}
+	if c.Charset != nil {
+		dst = append(dst, encoded...)
+	} else {
+		dst = append(dst, s...)
+	}
+	switch c.Format {
+	case StringNulTerminated:
+		dst = append(dst, 0)
+	case StringFixed:
+		for len(dst)-start < c.Width {
+			dst = append(dst, c.Pad)
+		}
+	}
+	return dst, nil
+}
+
+// Decode decodes the string at the start of data into dest and returns the
+// number of bytes consumed
+func (c StringCodec) Decode(dest *string, data []byte) (int, error) {
+	var raw []byte
+	var n int
+	var err error
+	switch c.Format {
+	case StringU8:
+		raw, n, err = c.decodePrefixed(data, 1)
+	case StringU16:
+		raw, n, err = c.decodePrefixed(data, 2)
+	case StringU32:
+		raw, n, err = c.decodePrefixed(data, 4)
+	case StringPacked:
+		var length uint64
+		var prefix int
+		if length, prefix, err = (LengthCodec{}).Unpack(data); err == nil {
+			raw, n, err = stringBody(data, length, prefix)
+		}
+	case StringNulTerminated:
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			return 0, fmt.Errorf("%w: no NUL terminator", ErrInvalidString)
+		}
+		n = end + 1
+		raw = data[:end]
+	case StringFixed:
+		if err := CheckBuffer(data, c.Width, 0); err != nil {
+			return 0, err
+		}
+		n = c.Width
+		raw = data[:n]
+		if c.Pad == 0 {
+			if end := bytes.IndexByte(raw, 0); end >= 0 {
+				raw = raw[:end]
+			}
+		} else {
+			for len(raw) > 0 && raw[len(raw)-1] == c.Pad {
+				raw = raw[:len(raw)-1]
+			}
+		}
+	default:
+		return 0, fmt.Errorf("%w: unknown format %d", ErrInvalidString,
+			c.Format)
+	}
+	if err != nil {
+		return 0, err
+	}
+	var s string
+	if c.Charset != nil {
+		if s, err = c.Charset.Decode(raw); err != nil {
+			return 0, err
+		}
+	} else {
+		s = string(raw)
+	}
+	if c.ValidateUTF8 && !utf8.ValidString(s) {
+		return 0, fmt.Errorf("%w: invalid UTF-8", ErrInvalidString)
+	}
+	*dest = s
+	return n, nil
+}
+
+// decodePrefixed returns the bytes of a string after a length prefix of size
+// bytes, and the size of the whole encoded string
+func (c StringCodec) decodePrefixed(data []byte, size int) ([]byte, int, error) {
+	if err := CheckBuffer(data, size, 0); err != nil {
+		return nil, 0, err
+	}
+	var length uint64
+	switch size {
+	case 1:
+		length = uint64(data[0])
+	case 2:
+		length = uint64(c.order().Uint16(data))
+	default:
+		length = uint64(c.order().Uint32(data))
+	}
+	return stringBody(data, length, size)
+}
+
+func stringBody(data []byte, length uint64, prefix int) ([]byte, int, error) {
+	if length > uint64(len(data)-prefix) {
+		return nil, 0, shortBufferOf(length, data, prefix)
+	}
+	n := prefix + int(length)
+	return data[prefix:n], n, nil
+}
+
+type latin1Charset struct{}
+
+// Latin1 is ISO-8859-1, each byte is the rune of the same value
+var Latin1 Charset = latin1Charset{}
+
+func (latin1Charset) Name() string {
+	return "ISO-8859-1"
+}
+
+func (latin1Charset) Encode(dst []byte, s string) ([]byte, error) {
+	for _, r := range s {
+		if r > 0xFF {
+			return dst, fmt.Errorf("%w: %U is not in ISO-8859-1",
+				ErrInvalidString, r)
+		}
+		dst = append(dst, byte(r))
+	}
+	return dst, nil
+}
+
+func (latin1Charset) Decode(data []byte) (string, error) {
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for _, b := range data {
+		sb.WriteRune(rune(b))
+	}
+	return sb.String(), nil
+}
+
+//go:generate go run ../internal/gen/gbk -mapping ../internal/gen/gbk/cp936.txt -o gbktable.go
+
+type gbkCharset struct{}
+
+// GBK is the simplified Chinese encoding of Windows code page 936
+var GBK Charset = gbkCharset{}
+
+// gbkEuro is the single byte of the euro sign in code page 936, it is not in
+// the GBK mapping of gbkTable
+const gbkEuro = 0x80
+
+var (
+	gbkEncodeOnce  sync.Once
+	gbkEncodeTable map[rune]uint16
+)
+
+func (gbkCharset) Name() string {
+	return "GBK"
+}
+
+func (gbkCharset) Encode(dst []byte, s string) ([]byte, error) {
+	gbkEncodeOnce.Do(func() {
+		gbkEncodeTable = make(map[rune]uint16, len(gbkTable))
+		for i, r := range gbkTable {
+			if _, ok := gbkEncodeTable[rune(r)]; r != 0 && !ok {
+				gbkEncodeTable[rune(r)] = uint16((i/191+0x81)<<8 | (i%191 + 0x40))
+			}
+		}
+	})
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			dst = append(dst, byte(r))
+			continue
+		}
+		if r == '€' {
+			dst = append(dst, gbkEuro)
+			continue
+		}
+		code, ok := gbkEncodeTable[r]
+		if !ok {
+			return dst, fmt.Errorf("%w: %U is not in GBK", ErrInvalidString, r)
+		}
+		dst = append(dst, byte(code>>8), byte(code))
+	}
+	return dst, nil
+}
+
+func (gbkCharset) Decode(data []byte) (string, error) {
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		if b < utf8.RuneSelf {
+			sb.WriteByte(b)
+			continue
+		}
+		if b == gbkEuro {
+			sb.WriteRune('€')
+			continue
+		}
+		var r uint16
+		if b >= 0x81 && b <= 0xFE && i+1 < len(data) &&
+			data[i+1] >= 0x40 && data[i+1] <= 0xFE {
+			r = gbkTable[int(b-0x81)*191+int(data[i+1]-0x40)]
+		}
+		if r == 0 {
+			return "", fmt.Errorf("%w: invalid GBK byte 0x%02X at %d",
+				ErrInvalidString, b, i)
+		}
+		sb.WriteRune(rune(r))
+		i++
+	}
+	return sb.String(), nil
+}
+
+type funcCharset struct {
+	name           string
+	encode, decode func([]byte) ([]byte, error)
+}
+
+// NewCharset returns a Charset from conversion functions, such as the Bytes
+// methods of an encoder and a decoder of golang.org/x/text. The functions
+// are called from any goroutine using the Charset.
+func NewCharset(name string, encode, decode func([]byte) ([]byte, error)) Charset {
+	return funcCharset{name, encode, decode}
+}
+
+func (c funcCharset) Name() string {
+	return c.name
+}
+
+func (c funcCharset) Encode(dst []byte, s string) ([]byte, error) {
+	data, err := c.encode([]byte(s))
+	if err != nil {
+		return dst, fmt.Errorf("%w: %s: %v", ErrInvalidString, c.name, err)
+	}
+	return append(dst, data...), nil
+}
+
+func (c funcCharset) Decode(data []byte) (string, error) {
+	s, err := c.decode(data)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidString, c.name, err)
+	}
+	return string(s), nil
+}
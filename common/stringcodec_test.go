@@ -0,0 +1,179 @@
+package common_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/newkedison/go-utils/common"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func checkStringCodec(a *assert.Assertions, c common.StringCodec, s string,
+	expected []byte) {
+	data, err := c.Append([]byte{0xAA}, s)
+	a.Nil(err)
+	a.Equal(data, append([]byte{0xAA}, expected...))
+	var result string
+	n, err := c.Decode(&result, append(data[1:], 0xBB))
+	a.Nil(err)
+	a.Equal(n, len(expected))
+	a.Equal(result, s)
+}
+
+func TestStringCodecFormat(t *testing.T) {
+	assert := assert.New(t)
+	checkStringCodec(assert, common.StringCodec{}, "AB",
+		[]byte{0x02, 0x00, 0x41, 0x42})
+	checkStringCodec(assert, common.StringCodec{Order: binary.BigEndian}, "AB",
+		[]byte{0x00, 0x02, 0x41, 0x42})
+	checkStringCodec(assert, common.StringCodec{Format: common.StringU8}, "AB",
+		[]byte{0x02, 0x41, 0x42})
+	checkStringCodec(assert, common.StringCodec{Format: common.StringU32}, "AB",
+		[]byte{0x02, 0x00, 0x00, 0x00, 0x41, 0x42})
+	long := strings.Repeat("A", 0x80)
+	checkStringCodec(assert, common.StringCodec{Format: common.StringPacked},
+		long, append([]byte{0x80, 0x80}, long...))
+	checkStringCodec(assert,
+		common.StringCodec{Format: common.StringNulTerminated}, "AB",
+		[]byte{0x41, 0x42, 0x00})
+	checkStringCodec(assert, common.StringCodec{Format: common.StringFixed,
+		Width: 4}, "AB", []byte{0x41, 0x42, 0x00, 0x00})
+	checkStringCodec(assert, common.StringCodec{Format: common.StringFixed,
+		Width: 4, Pad: ' '}, "AB", []byte{0x41, 0x42, 0x20, 0x20})
+	checkStringCodec(assert, common.StringCodec{Format: common.StringFixed,
+		Width: 2}, "AB", []byte{0x41, 0x42})
+
+	var s string
+	n, err := common.StringCodec{Format: common.StringFixed, Width: 4}.Decode(
+		&s, []byte{0x41, 0x00, 0x42, 0x00})
+	assert.Nil(err)
+	assert.Equal(n, 4)
+	assert.Equal(s, "A")
+}
+
+func TestStringCodecError(t *testing.T) {
+	assert := assert.New(t)
+	_, err := common.StringCodec{Format: common.StringU8}.Encode(
+		strings.Repeat("A", 256))
+	assert.Equal(err, common.ErrStringTooLong)
+	_, err = common.StringCodec{}.Encode(strings.Repeat("A", 65536))
+	assert.Equal(err, common.ErrStringTooLong)
+	_, err = common.StringCodec{Format: common.StringFixed, Width: 1}.Encode(
+		"AB")
+	assert.Equal(err, common.ErrStringTooLong)
+	_, err = common.StringCodec{Format: common.StringNulTerminated}.Encode(
+		"A\x00B")
+	assert.True(errors.Is(err, common.ErrInvalidString))
+	_, err = common.StringCodec{ValidateUTF8: true}.Encode("\xFF")
+	assert.True(errors.Is(err, common.ErrInvalidString))
+	_, err = common.StringCodec{Format: 100}.Encode("A")
+	assert.True(errors.Is(err, common.ErrInvalidString))
+
+	var s string
+	_, err = common.StringCodec{ValidateUTF8: true}.Decode(&s,
+		[]byte{0x01, 0x00, 0xFF})
+	assert.True(errors.Is(err, common.ErrInvalidString))
+	_, err = common.StringCodec{Format: common.StringNulTerminated}.Decode(&s,
+		[]byte{0x41})
+	assert.EqualError(err, "Invalid string: no NUL terminator")
+	for _, format := range []common.StringFormat{common.StringU8,
+		common.StringU16, common.StringU32, common.StringPacked} {
+		c := common.StringCodec{Format: format}
+		data, err := c.Encode("ABC")
+		assert.Nil(err)
+		for i := range data {
+			_, err = c.Decode(&s, data[:i])
+			assert.NotNil(err)
+		}
+	}
+	_, err = common.StringCodec{}.Decode(&s, []byte{0x03, 0x00, 0x41})
+	assert.Equal(err,
+		&common.ShortBufferError{Required: 3, Offered: 3, Offset: 2})
+	_, err = common.StringCodec{Format: common.StringFixed, Width: 2}.Decode(
+		&s, []byte{0x41})
+	assert.True(errors.Is(err, common.ErrShortBuffer))
+	_, err = common.StringCodec{Format: 100}.Decode(&s, []byte{0x00})
+	assert.True(errors.Is(err, common.ErrInvalidString))
+}
+
+func TestMarshalStringTooLong(t *testing.T) {
+	assert := assert.New(t)
+	assert.Panics(func() { common.MarshalString(strings.Repeat("A", 65536)) })
+}
+
+func TestLatin1(t *testing.T) {
+	assert := assert.New(t)
+	c := common.StringCodec{Format: common.StringU8, Charset: common.Latin1}
+	checkStringCodec(assert, c, "Grüße", []byte{0x05, 0x47, 0x72, 0xFC, 0xDF,
+		0x65})
+	_, err := c.Encode("你好")
+	assert.EqualError(err, "Invalid string: U+4F60 is not in ISO-8859-1")
+	assert.Equal(common.Latin1.Name(), "ISO-8859-1")
+}
+
+func TestGBK(t *testing.T) {
+	assert := assert.New(t)
+	c := common.StringCodec{Format: common.StringNulTerminated,
+		Charset: common.GBK}
+	checkStringCodec(assert, c, "温度A1", []byte{0xCE, 0xC2, 0xB6, 0xC8, 0x41,
+		0x31, 0x00})
+	checkStringCodec(assert, c, "你好", []byte{0xC4, 0xE3, 0xBA, 0xC3, 0x00})
+	_, err := c.Encode("\U0001F600")
+	assert.True(errors.Is(err, common.ErrInvalidString))
+	var s string
+	_, err = c.Decode(&s, []byte{0xC4, 0x00})
+	assert.EqualError(err, "Invalid string: invalid GBK byte 0xC4 at 0")
+	_, err = c.Decode(&s, []byte{0xFF, 0x00})
+	assert.True(errors.Is(err, common.ErrInvalidString))
+	// the single byte euro sign of code page 936
+	checkStringCodec(assert, c, "5€", []byte{0x35, 0x80, 0x00})
+	assert.Equal(common.GBK.Name(), "GBK")
+}
+
+func TestNewCharset(t *testing.T) {
+	assert := assert.New(t)
+	upper := common.NewCharset("upper",
+		func(b []byte) ([]byte, error) { return bytes.ToUpper(b), nil },
+		func(b []byte) ([]byte, error) {
+			if len(b) == 0 {
+				return nil, errors.New("empty")
+			}
+			return bytes.ToLower(b), nil
+		})
+	c := common.StringCodec{Format: common.StringU8, Charset: upper}
+	data, err := c.Encode("ab")
+	assert.Nil(err)
+	assert.Equal(data, []byte{0x02, 0x41, 0x42})
+	var s string
+	_, err = c.Decode(&s, data)
+	assert.Nil(err)
+	assert.Equal(s, "ab")
+	_, err = c.Decode(&s, []byte{0x00})
+	assert.EqualError(err, "Invalid string: upper: empty")
+	assert.Equal(upper.Name(), "upper")
+}
+
+func FuzzStringCodecDecode(f *testing.F) {
+	f.Add([]byte{0x02, 0x00, 0x41, 0x42})
+	f.Add([]byte{0xF0, 0xFF, 0xFF, 0xFF, 0xFF, 0x41})
+	f.Add([]byte{0xC4, 0xE3, 0xBA, 0x00})
+	codecs := []common.StringCodec{
+		{},
+		{Format: common.StringU8, ValidateUTF8: true},
+		{Format: common.StringU32, Order: binary.BigEndian},
+		{Format: common.StringPacked, Charset: common.Latin1},
+		{Format: common.StringNulTerminated, Charset: common.GBK},
+		{Format: common.StringFixed, Width: 4, Pad: ' '},
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, c := range codecs {
+			var s string
+			n, err := c.Decode(&s, data)
+			if err == nil && (n < 0 || n > len(data)) {
+				t.Fatalf("%+v consumed %d of %d bytes", c, n, len(data))
+			}
+		}
+	})
+}
@@ -2,17 +2,25 @@ package common
 
 import (
 	"encoding/hex"
+	"errors"
 	"strings"
 )
 
+// ErrHexByteLength is returned by HexStringToByte for an empty string
+var ErrHexByteLength = errors.New("Empty hex string")
+
 func ByteToHexString(b byte) string {
 	return strings.ToUpper(hex.EncodeToString([]byte{b}))
 }
 
+// HexStringToByte returns the first byte of the hex string s
 func HexStringToByte(s string) (byte, error) {
 	b, err := hex.DecodeString(s)
 	if err != nil {
 		return 0, err
 	}
-	return b[0], err
+	if len(b) == 0 {
+		return 0, ErrHexByteLength
+	}
+	return b[0], nil
 }
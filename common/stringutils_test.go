@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"github.com/newkedison/go-utils/common"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -36,3 +37,24 @@ func TestHexStringToByte(t *testing.T) {
 	_, err = common.HexStringToByte("0Z")
 	assert.EqualError(err, "encoding/hex: invalid byte: U+005A 'Z'")
 }
+
+func TestHexStringToByteLength(t *testing.T) {
+	assert := assert.New(t)
+	_, err := common.HexStringToByte("")
+	assert.Equal(err, common.ErrHexByteLength)
+	// longer strings return their first byte
+	testOneValidHexString(assert, "0102", 0x01)
+}
+
+func FuzzHexStringToByte(f *testing.F) {
+	f.Add("")
+	f.Add("A5")
+	f.Add("0Z")
+	f.Add("0000")
+	f.Fuzz(func(t *testing.T, s string) {
+		b, err := common.HexStringToByte(s)
+		if err == nil && common.ByteToHexString(b) != strings.ToUpper(s[:2]) {
+			t.Fatalf("%q decoded as %02X", s, b)
+		}
+	})
+}
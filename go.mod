@@ -1,9 +1,13 @@
 module github.com/newkedison/go-utils
 
-go 1.12
+go 1.18
 
 require (
 	github.com/golang/protobuf v1.3.1
-	github.com/smartystreets/goconvey v0.0.0-20190330032615-68dc04aab96a // indirect
 	github.com/stretchr/testify v1.3.0
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
@@ -0,0 +1,125 @@
+// gbk generates the GBK decoding table of common/gbktable.go from a mapping
+// file in the format of the Unicode CP936.TXT, see cp936.txt.
+//
+// Usage:
+//
+//	gbk [-mapping cp936.txt] [-o gbktable.go]
+//
+// It is run by go generate in the common package.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	firstLead  = 0x81
+	lastLead   = 0xFE
+	firstTrail = 0x40
+	lastTrail  = 0xFE
+	// trailCount is the number of trail bytes of a lead byte in the table
+	trailCount = lastTrail - firstTrail + 1
+	// valuesPerLine is the number of table values on a line of the output
+	valuesPerLine = 12
+)
+
+// readMapping returns the Unicode code point of every GBK double byte code
+// of the mapping file, 0 for unassigned codes
+func readMapping(r io.Reader) ([]uint16, error) {
+	table := make([]uint16, (lastLead-firstLead+1)*trailCount)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected 2 columns", line)
+		}
+		code, err := strconv.ParseUint(fields[0], 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid code %q", line, fields[0])
+		}
+		r, err := strconv.ParseUint(fields[1], 0, 16)
+		if err != nil || r == 0 {
+			return nil, fmt.Errorf("line %d: invalid code point %q", line,
+				fields[1])
+		}
+		lead, trail := code>>8, code&0xFF
+		if lead < firstLead || lead > lastLead || trail < firstTrail ||
+			trail > lastTrail {
+			return nil, fmt.Errorf("line %d: %#04x is not a double byte code",
+				line, code)
+		}
+		i := (lead-firstLead)*trailCount + trail - firstTrail
+		if table[i] != 0 {
+			return nil, fmt.Errorf("line %d: duplicate code %#04x", line, code)
+		}
+		table[i] = uint16(r)
+	}
+	return table, scanner.Err()
+}
+
+// generate returns the Go source of the table
+func generate(table []uint16) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`// Code generated from the GBK (CP936) mapping. DO NOT EDIT.
+
+package common
+
+// gbkTable maps the GBK double byte codes to Unicode, the index of lead byte
+// l and trail byte t is (l-0x81)*191+(t-0x40), 0 for unassigned codes
+var gbkTable = [...]uint16{
+`)
+	for i, v := range table {
+		if i%valuesPerLine == 0 {
+			buf.WriteString("\t")
+		}
+		fmt.Fprintf(&buf, "0x%04X,", v)
+		if i%valuesPerLine == valuesPerLine-1 || i == len(table)-1 {
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString(" ")
+		}
+	}
+	buf.WriteString("}\n")
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	mapping := flag.String("mapping", "cp936.txt", "the mapping file to read")
+	output := flag.String("o", "gbktable.go", "the Go file to write")
+	flag.Parse()
+
+	f, err := os.Open(*mapping)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	table, err := readMapping(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *mapping, err)
+		os.Exit(1)
+	}
+	src, err := generate(table)
+	if err == nil {
+		err = os.WriteFile(*output, src, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
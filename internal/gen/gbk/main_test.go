@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+	f, err := os.Open("cp936.txt")
+	if !assert.Nil(err) {
+		return
+	}
+	defer f.Close()
+	table, err := readMapping(f)
+	assert.Nil(err)
+	// 丂 and 龥
+	assert.EqualValues(table[0], 0x4E02)
+	assert.EqualValues(table[(0xFD-0x81)*191+0x9B-0x40], 0x9FA5)
+	src, err := generate(table)
+	assert.Nil(err)
+	// the committed table must be regenerated after a change of cp936.txt
+	committed, err := os.ReadFile("../../../common/gbktable.go")
+	assert.Nil(err)
+	assert.True(string(src) == string(committed),
+		"common/gbktable.go is out of date, run go generate ./common")
+}
+
+func TestReadMappingError(t *testing.T) {
+	assert := assert.New(t)
+	for _, mapping := range []string{
+		"0x8140",
+		"0x8140\t0xZZ",
+		"0x8140\t0x0000",
+		"0x0041\t0x0041",
+		"0x8140\t0x4E02\n0x8140\t0x4E02",
+	} {
+		_, err := readMapping(strings.NewReader(mapping))
+		assert.NotNil(err, mapping)
+	}
+	table, err := readMapping(strings.NewReader("# comment\n\n0x8141 0x4E04"))
+	assert.Nil(err)
+	assert.EqualValues(table[1], 0x4E04)
+}